@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// account is the address shown on the Account page.
+type account struct {
+	mu      sync.Mutex
+	watched string
+	// changed wakes the writer up when another address gets watched
+	changed chan struct{}
+}
+
+// accountPage creates the page showing the state of an account through the
+// Cosmos SDK REST API.
+func accountPage(ctx context.Context, delay time.Duration) (page, *account) {
+	accountWidget, err := text.New(text.WrapAtRunes())
+	if err != nil {
+		panic(err)
+	}
+	if err := accountWidget.Write("Press a and enter an address to watch its account.\n"); err != nil {
+		panic(err)
+	}
+
+	a := &account{changed: make(chan struct{}, 1)}
+	go writeAccount(ctx, a, accountWidget, delay)
+
+	return page{
+		name: "Account",
+		layout: []container.Option{
+			container.Border(linestyle.Light),
+			container.BorderTitle("Account"),
+			container.PlaceWidget(accountWidget),
+		},
+	}, a
+}
+
+// watch makes address the account that is shown.
+func (a *account) watch(address string) {
+	a.mu.Lock()
+	a.watched = address
+	a.mu.Unlock()
+
+	select {
+	case a.changed <- struct{}{}:
+	default:
+	}
+}
+
+// address returns the watched address.
+func (a *account) address() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.watched
+}
+
+// writeAccount writes the balances, auth info and staking positions of the
+// watched account. Exits when the context expires.
+func writeAccount(ctx context.Context, a *account, t *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.changed:
+		case <-ctx.Done():
+			return
+		}
+
+		address := a.address()
+		if address == "" {
+			continue
+		}
+
+		result, err := queryAccount(address)
+		if err != nil {
+			result = fmt.Sprintf("ACCOUNT %s\n\n✖️ %s\n", address, err)
+		}
+
+		t.Reset()
+		if err := t.Write(result); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// queryAccount describes the account with the address.
+func queryAccount(address string) (string, error) {
+	authRPC, err := getFromAPI("/cosmos/auth/v1beta1/accounts/" + address)
+	if err != nil {
+		return "", err
+	}
+	balancesRPC, err := getFromAPI("/cosmos/bank/v1beta1/balances/" + address)
+	if err != nil {
+		return "", err
+	}
+	// an account without any stake is no error, the sections stay empty then
+	delegationsRPC, _ := getFromAPI("/cosmos/staking/v1beta1/delegations/" + address)
+	unbondingRPC, _ := getFromAPI("/cosmos/staking/v1beta1/delegators/" + address + "/unbonding_delegations")
+	rewardsRPC, _ := getFromAPI("/cosmos/distribution/v1beta1/delegators/" + address + "/rewards")
+	stakingParamsRPC, _ := getFromAPI("/cosmos/staking/v1beta1/params")
+	bondDenom := gjson.Get(stakingParamsRPC, "params.bond_denom").String()
+
+	baseAccount := baseAccount(gjson.Get(authRPC, "account"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "ACCOUNT %s\n\n", address)
+	fmt.Fprintf(&b, "Type            %s\n", gjson.Get(authRPC, "account.@type").String())
+	fmt.Fprintf(&b, "Number          %s\n", baseAccount.Get("account_number").String())
+	fmt.Fprintf(&b, "Sequence        %s\n", baseAccount.Get("sequence").String())
+	fmt.Fprintf(&b, "Balances        %s\n", formatCoins(gjson.Get(balancesRPC, "balances")))
+	fmt.Fprintf(&b, "Rewards         %s\n", formatCoins(gjson.Get(rewardsRPC, "total")))
+
+	b.WriteString("\nDelegations\n")
+	for _, d := range gjson.Get(delegationsRPC, "delegation_responses").Array() {
+		fmt.Fprintf(&b, "  %s  %s\n",
+			d.Get("delegation.validator_address").String(),
+			formatCoin(d.Get("balance.amount").String(), d.Get("balance.denom").String()),
+		)
+	}
+
+	b.WriteString("\nUnbonding\n")
+	for _, u := range gjson.Get(unbondingRPC, "unbonding_responses").Array() {
+		for _, entry := range u.Get("entries").Array() {
+			fmt.Fprintf(&b, "  %s  %s until %s\n",
+				u.Get("validator_address").String(),
+				formatCoin(entry.Get("balance").String(), bondDenom),
+				formatTime(entry.Get("completion_time").String()),
+			)
+		}
+	}
+
+	b.WriteString("\nRewards by Validator\n")
+	for _, r := range gjson.Get(rewardsRPC, "rewards").Array() {
+		fmt.Fprintf(&b, "  %s  %s\n", r.Get("validator_address").String(), formatCoins(r.Get("reward")))
+	}
+
+	return b.String(), nil
+}
+
+// baseAccount finds the base account that vesting and module accounts wrap.
+func baseAccount(account gjson.Result) gjson.Result {
+	for _, path := range []string{"base_vesting_account.base_account", "base_account"} {
+		if base := account.Get(path); base.Exists() {
+			return base
+		}
+	}
+
+	return account
+}
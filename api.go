@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// apiBlocks is the number of blocks /api/blocks returns without a limit.
+const apiBlocks = 20
+
+// missedAlertPercent is the share of the recent blocks a validator may miss
+// before it is alerted.
+const missedAlertPercent = 50
+
+// missedAlertBlocks is the number of blocks the missed blocks must be counted
+// in before they are alerted.
+const missedAlertBlocks = 10
+
+// jsonAPI serves the state gex derived from the chain, e.g. the averages, the
+// missed blocks and the message counts, as read-only JSON.
+type jsonAPI struct {
+	info    Info
+	network string
+}
+
+// register adds the routes of the API to a mux.
+func (a *jsonAPI) register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/summary", a.get(a.summary))
+	mux.HandleFunc("/api/blocks", a.get(a.blocks))
+	mux.HandleFunc("/api/txs", a.get(a.txs))
+	mux.HandleFunc("/api/validators", a.get(a.validators))
+	mux.HandleFunc("/api/alerts", a.get(a.alerts))
+}
+
+// listenAPI serves the API on the -api-addr address next to the dashboard.
+func listenAPI(info Info, network string) error {
+	listener, err := net.Listen("tcp", *givenAPIAddr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	(&jsonAPI{info: info, network: network}).register(mux)
+	go http.Serve(listener, mux)
+
+	return nil
+}
+
+// get answers the GET requests of a route with the JSON of its result.
+func (a *jsonAPI) get(result func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		v, err := result(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(body, '\n'))
+	}
+}
+
+// apiSummary is the state of the chain and the averages since gex started.
+type apiSummary struct {
+	Network               string  `json:"network"`
+	Endpoint              string  `json:"endpoint"`
+	EndpointsHealthy      int     `json:"endpoints_healthy"`
+	Endpoints             int     `json:"endpoints"`
+	Polling               bool    `json:"polling"`
+	Height                int64   `json:"height"`
+	LastBlockAt           string  `json:"last_block_at"`
+	BlocksSeen            int     `json:"blocks_seen"`
+	SecondsPerBlock       float64 `json:"seconds_per_block"`
+	RecentSecondsPerBlock float64 `json:"recent_seconds_per_block"`
+	TxsSeen               uint64  `json:"txs_seen"`
+	MaxGasPerBlock        int64   `json:"max_gas_per_block"`
+	AvgGasWantedPerBlock  int64   `json:"avg_gas_wanted_per_block"`
+	AvgGasWantedPerTx     int64   `json:"avg_gas_wanted_per_tx"`
+	GasUsedPercent        float64 `json:"gas_used_percent"`
+	Alerts                int     `json:"alerts"`
+}
+
+// summary returns the averages since gex started, like the Overview page.
+func (a *jsonAPI) summary(r *http.Request) (interface{}, error) {
+	blocks := a.info.blocks.snapshot()
+	txs := a.info.transactions.count()
+	healthy, total := endpoints.healthy()
+	used, wanted := a.info.gas.totals()
+
+	s := apiSummary{
+		Network:               a.network,
+		Endpoint:              getHttpUrl(),
+		EndpointsHealthy:      healthy,
+		Endpoints:             total,
+		Polling:               eventPolling(),
+		Height:                blocks.height,
+		LastBlockAt:           blocks.lastBlockTime.UTC().Format(time.RFC3339),
+		BlocksSeen:            blocks.amount,
+		RecentSecondsPerBlock: a.info.signatures.averageInterval().Seconds(),
+		TxsSeen:               txs,
+		MaxGasPerBlock:        blocks.maxGasWanted,
+		GasUsedPercent:        gasEfficiency(used, wanted),
+		Alerts:                len(a.currentAlerts()),
+	}
+	// don't divide by 0
+	if blocks.amount > 0 {
+		s.SecondsPerBlock = float64(blocks.secondsPassed) / float64(blocks.amount)
+		s.AvgGasWantedPerBlock = blocks.totalGasWanted / int64(blocks.amount)
+	}
+	if txs > 0 {
+		s.AvgGasWantedPerTx = blocks.totalGasWanted / int64(txs)
+	}
+
+	return s, nil
+}
+
+// apiBlock is a recent block.
+type apiBlock struct {
+	Height          int64             `json:"height"`
+	Time            string            `json:"time"`
+	IntervalSeconds float64           `json:"interval_seconds"`
+	Proposer        string            `json:"proposer"`
+	Txs             int               `json:"txs"`
+	GasWanted       int64             `json:"gas_wanted"`
+	GasUsed         int64             `json:"gas_used"`
+	Fees            map[string]string `json:"fees"`
+	Signatures      int               `json:"signatures"`
+	// validators that didn't sign the previous block, null when unknown
+	Absent []string `json:"absent"`
+}
+
+// blocks returns the recent blocks with their gas, fees and signatures.
+func (a *jsonAPI) blocks(r *http.Request) (interface{}, error) {
+	limit := apiBlocks
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid limit %q", l)
+		}
+		limit = n
+	}
+
+	gasBlocks, _ := a.info.gas.recent()
+	gas := map[int64]blockGas{}
+	for _, b := range gasBlocks {
+		gas[b.height] = b
+	}
+	// stats copies the amounts, the encoder can read them while fees are counted
+	_, feeBlocks := a.info.fees.stats()
+	fees := map[int64]map[string]string{}
+	for _, b := range feeBlocks {
+		fees[b.height] = b.amounts
+	}
+
+	blocks := []apiBlock{}
+	for _, b := range a.info.signatures.recent() {
+		if len(blocks) == limit {
+			break
+		}
+		block := apiBlock{
+			Height:          b.height,
+			Time:            b.time.UTC().Format(time.RFC3339Nano),
+			IntervalSeconds: b.interval.Seconds(),
+			Proposer:        b.proposer,
+			Txs:             b.txs,
+			GasWanted:       gas[b.height].wanted,
+			GasUsed:         gas[b.height].used,
+			Fees:            fees[b.height],
+			Signatures:      b.signed,
+		}
+		if b.attributed {
+			block.Absent = append([]string{}, b.absent...)
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// apiTxs are the counts of the confirmed transactions.
+type apiTxs struct {
+	TxsSeen       uint64            `json:"txs_seen"`
+	WindowSeconds float64           `json:"window_seconds"`
+	Messages      []apiMessageCount `json:"messages"`
+	Failures      []apiFailureCount `json:"failures"`
+	GasPrices     []apiGasPrice     `json:"gas_prices"`
+	GasUsed       []apiGasBucket    `json:"gas_used"`
+}
+
+// apiMessageCount are the messages of a type.
+type apiMessageCount struct {
+	Type      string `json:"type"`
+	InWindow  int    `json:"in_window"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+}
+
+// apiFailureCount are the failed transactions of an error.
+type apiFailureCount struct {
+	Reason   string `json:"reason"`
+	InWindow int    `json:"in_window"`
+	Total    int    `json:"total"`
+}
+
+// apiGasPrice are the gas prices paid in a denom in the window.
+type apiGasPrice struct {
+	Denom  string  `json:"denom"`
+	Txs    int     `json:"txs"`
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	Max    float64 `json:"max"`
+}
+
+// apiGasBucket is a bar of the gas used per tx histogram.
+type apiGasBucket struct {
+	// upper bound of the gas used, null for the transactions above the last
+	MaxGas *int64 `json:"max_gas"`
+	Txs    int    `json:"txs"`
+}
+
+// txs returns the counts of the message types and the errors, the gas prices
+// and the histogram of the gas used.
+func (a *jsonAPI) txs(r *http.Request) (interface{}, error) {
+	t := apiTxs{
+		TxsSeen:       a.info.transactions.count(),
+		WindowSeconds: messageWindow.Seconds(),
+		Messages:      []apiMessageCount{},
+		Failures:      []apiFailureCount{},
+		GasPrices:     []apiGasPrice{},
+		GasUsed:       []apiGasBucket{},
+	}
+	for _, m := range a.info.messages.stats() {
+		t.Messages = append(t.Messages, apiMessageCount{Type: m.msgType, InWindow: m.inWindow, Succeeded: m.succeeded, Failed: m.failed})
+	}
+	for _, f := range a.info.failures.stats() {
+		t.Failures = append(t.Failures, apiFailureCount{Reason: f.reason, InWindow: f.inWindow, Total: f.total})
+	}
+	prices, _ := a.info.fees.stats()
+	for _, p := range prices {
+		t.GasPrices = append(t.GasPrices, apiGasPrice{Denom: p.denom, Txs: p.txs, Min: p.min, Median: p.median, Max: p.max})
+	}
+	_, histogram := a.info.gas.recent()
+	for i, txs := range histogram {
+		bucket := apiGasBucket{Txs: txs}
+		if i < len(gasBuckets) {
+			bucket.MaxGas = &gasBuckets[i]
+		}
+		t.GasUsed = append(t.GasUsed, bucket)
+	}
+
+	return t, nil
+}
+
+// apiValidators are the validators of the latest set.
+type apiValidators struct {
+	Blocks     int            `json:"blocks"`
+	Validators []apiValidator `json:"validators"`
+}
+
+// apiValidator is a validator with the recent blocks it missed.
+type apiValidator struct {
+	Address       string  `json:"address"`
+	VotingPower   int64   `json:"voting_power"`
+	SharePercent  float64 `json:"share_percent"`
+	Missed        int     `json:"missed"`
+	MissedPercent float64 `json:"missed_percent"`
+}
+
+// validators returns the validators with the recent blocks they missed.
+func (a *jsonAPI) validators(r *http.Request) (interface{}, error) {
+	signing, blocks := a.info.signatures.validators()
+
+	totalPower := int64(0)
+	for _, v := range signing {
+		totalPower += v.power
+	}
+
+	result := apiValidators{Blocks: blocks, Validators: []apiValidator{}}
+	for _, v := range signing {
+		validator := apiValidator{Address: v.address, VotingPower: v.power, Missed: v.missed}
+		// don't divide by 0
+		if totalPower > 0 {
+			validator.SharePercent = float64(v.power) / float64(totalPower) * 100
+		}
+		if blocks > 0 {
+			validator.MissedPercent = float64(v.missed) / float64(blocks) * 100
+		}
+		result.Validators = append(result.Validators, validator)
+	}
+
+	return result, nil
+}
+
+// apiAlert is a condition that needs attention.
+type apiAlert struct {
+	Level   string `json:"level"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// alerts returns what needs attention.
+func (a *jsonAPI) alerts(r *http.Request) (interface{}, error) {
+	return a.currentAlerts(), nil
+}
+
+// currentAlerts checks for a halted chain, RPC endpoints down, validators
+// missing blocks and headers failing the verification.
+func (a *jsonAPI) currentAlerts() []apiAlert {
+	alerts := []apiAlert{}
+	blocks := a.info.blocks.snapshot()
+
+	// no new block for a few block times, like the upgrade banner
+	if blocks.amount > 0 {
+		secondsPerBlock := float64(blocks.secondsPassed) / float64(blocks.amount)
+		if since := time.Since(blocks.lastBlockTime); secondsPerBlock > 0 && since.Seconds() > 3*secondsPerBlock {
+			alerts = append(alerts, apiAlert{
+				Level:   "critical",
+				Kind:    "chain_halted",
+				Message: fmt.Sprintf("no new block since %s at height %d", since.Round(time.Second), blocks.height),
+			})
+		}
+	}
+
+	if healthy, total := endpoints.healthy(); total > 1 && healthy < total {
+		level := "warning"
+		if healthy == 0 {
+			level = "critical"
+		}
+		alerts = append(alerts, apiAlert{
+			Level:   level,
+			Kind:    "endpoints_down",
+			Message: fmt.Sprintf("%d of %d RPC endpoints are down, catching up or behind", total-healthy, total),
+		})
+	}
+
+	if signing, n := a.info.signatures.validators(); n >= missedAlertBlocks {
+		for _, v := range signing {
+			if v.missed*100 < missedAlertPercent*n {
+				break
+			}
+			alerts = append(alerts, apiAlert{
+				Level:   "warning",
+				Kind:    "validator_missing_blocks",
+				Message: fmt.Sprintf("validator %s missed %d of the last %d blocks", v.address, v.missed, n),
+			})
+		}
+	}
+
+	if lc := a.info.verifier; lc != nil {
+		if failure := lc.failure(); failure > 0 && blocks.height-failure < signatureWindow {
+			alerts = append(alerts, apiAlert{
+				Level:   "critical",
+				Kind:    "verification_failed",
+				Message: fmt.Sprintf("the header of block %d failed the verification", failure),
+			})
+		}
+	}
+
+	return alerts
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestAPIBlocksFees(t *testing.T) {
+	info := Info{
+		gas:        new(Gas),
+		fees:       new(Fees),
+		signatures: &Signatures{blocks: []blockSummary{{height: 1}, {height: 2}}},
+	}
+	tx := encodeTx(nil, []txCoin{{"uatom", "1000"}}, 100000)
+	info.fees.count(feeEvent(2, tx, 0))
+	a := &jsonAPI{info: info}
+
+	// the fees of the latest block keep being counted while it's encoded
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			info.fees.count(feeEvent(2, tx, 0))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		blocks, err := a.blocks(httptest.NewRequest("GET", "/api/blocks", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := json.Marshal(blocks); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	blocks, err := a.blocks(httptest.NewRequest("GET", "/api/blocks", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info.fees.count(feeEvent(2, tx, 0))
+	latest := blocks.([]apiBlock)[0]
+	if latest.Height != 2 || latest.Fees["uatom"] != "101000" {
+		t.Errorf("latest block %d has the fees %v, want 2 with 101000uatom", latest.Height, latest.Fees)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// bech32Charset is the alphabet of the data part of a bech32 string.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Decode splits a bech32 string into its human readable part and the
+// 5 bit groups of its data, the checksum is verified and removed.
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("bech32 string has mixed case")
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndex(s, "1")
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, fmt.Errorf("invalid bech32 string %q", s)
+	}
+
+	hrp := s[:sep]
+	data := make([]byte, 0, len(s)-sep-1)
+	for _, c := range s[sep+1:] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return "", nil, fmt.Errorf("invalid bech32 character %q", c)
+		}
+		data = append(data, byte(i))
+	}
+	if bech32Polymod(append(bech32ExpandHRP(hrp), data...)) != 1 {
+		return "", nil, fmt.Errorf("invalid bech32 checksum of %q", s)
+	}
+
+	return hrp, data[:len(data)-6], nil
+}
+
+// bech32Encode encodes the 5 bit groups of data with the human readable part.
+func bech32Encode(hrp string, data []byte) string {
+	values := append(bech32ExpandHRP(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ 1
+
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, d := range data {
+		b.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+
+	return b.String()
+}
+
+// convertBech32Prefix re-encodes the address with another human readable
+// part, e.g. an operator address as the account address of the operator.
+func convertBech32Prefix(address, hrp string) (string, error) {
+	_, data, err := bech32Decode(address)
+	if err != nil {
+		return "", err
+	}
+
+	return bech32Encode(hrp, data), nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c>>5))
+	}
+	expanded = append(expanded, 0)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c&31))
+	}
+
+	return expanded
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBech32Decode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		hrp   string
+		valid bool
+	}{
+		// the test vectors of BIP 173
+		{"upper case", "A12UEL5L", "a", true},
+		{"lower case", "a12uel5l", "a", true},
+		{"all characters", "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", "abcdef", true},
+		{"separator in hrp", "split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", "split", true},
+		{"symbol hrp", "?1ezyfcl", "?", true},
+		{"empty hrp", "1nwldj5", "", false},
+		{"mixed case", "A12uEL5L", "", false},
+		{"invalid character", "x1b4n0q5v", "", false},
+		{"short checksum", "li1dgmt3", "", false},
+		{"invalid checksum", "a12uel5m", "", false},
+		{"no separator", "pzry9x0s0muk", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hrp, _, err := bech32Decode(tt.input)
+			if (err == nil) != tt.valid {
+				t.Fatalf("bech32Decode(%q) error = %v, want valid %v", tt.input, err, tt.valid)
+			}
+			if tt.valid && hrp != tt.hrp {
+				t.Errorf("bech32Decode(%q) hrp = %q, want %q", tt.input, hrp, tt.hrp)
+			}
+		})
+	}
+}
+
+func TestBech32Encode(t *testing.T) {
+	tests := []string{
+		"a12uel5l",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	}
+
+	for _, s := range tests {
+		hrp, data, err := bech32Decode(s)
+		if err != nil {
+			t.Fatalf("bech32Decode(%q) error = %v", s, err)
+		}
+		if got := bech32Encode(hrp, data); got != s {
+			t.Errorf("bech32Encode(%q, ...) = %q, want %q", hrp, got, s)
+		}
+	}
+}
+
+func TestConvertBech32Prefix(t *testing.T) {
+	data := make([]byte, 32)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	operator := bech32Encode("cosmosvaloper", data)
+
+	account, err := convertBech32Prefix(operator, "cosmos")
+	if err != nil {
+		t.Fatalf("convertBech32Prefix(%q) error = %v", operator, err)
+	}
+	hrp, got, err := bech32Decode(account)
+	if err != nil {
+		t.Fatalf("bech32Decode(%q) error = %v", account, err)
+	}
+	if hrp != "cosmos" || !bytes.Equal(got, data) {
+		t.Errorf("convertBech32Prefix(%q) = %q, want the same data with hrp cosmos", operator, account)
+	}
+
+	if _, err := convertBech32Prefix("cosmosvaloper1invalid", "cosmos"); err == nil {
+		t.Error("convertBech32Prefix of an invalid address didn't fail")
+	}
+}
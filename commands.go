@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/jedib0t/go-pretty/text"
+	"github.com/tidwall/gjson"
+)
+
+// command prints what it queried as tables or as the JSON of the RPC and
+// exits, e.g. for scripts.
+type command struct {
+	usage string
+	args  int
+	run   func(args []string) (string, []table.Writer, error)
+}
+
+// commands are the subcommands of gex, without one gex starts the dashboard.
+var commands = map[string]command{
+	"status":     {usage: "status", run: statusCommand},
+	"validators": {usage: "validators", run: validatorsCommand},
+	"peers":      {usage: "peers", run: peersCommand},
+	"block":      {usage: "block <height>", args: 1, run: blockCommand},
+	"tx":         {usage: "tx <hash>", args: 1, run: txCommand},
+	"params":     {usage: "params", run: paramsCommand},
+}
+
+// commandArgs splits the arguments of a subcommand from the flags given after
+// it, e.g. gex block 100 -json.
+func commandArgs(args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		if err := flag.CommandLine.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flag.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+
+	return positional, nil
+}
+
+// runCommand runs a subcommand and returns the exit code.
+func runCommand(name string, args []string) int {
+	cmd, ok := commands[name]
+	if !ok {
+		names := []string{serveCommand}
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "unknown command %q, expected one of %s\n", name, strings.Join(names, ", "))
+		return 2
+	}
+	if len(args) != cmd.args {
+		fmt.Fprintf(os.Stderr, "usage: gex [flags] %s\n", cmd.usage)
+		return 2
+	}
+
+	raw, tables, err := cmd.run(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *givenJSON {
+		var b bytes.Buffer
+		if err := json.Indent(&b, []byte(raw), "", "  "); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(b.String())
+		return 0
+	}
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(t.Render())
+	}
+
+	return 0
+}
+
+// newTable creates a table with the header, the columns from right are
+// aligned right.
+func newTable(right int, header ...interface{}) table.Writer {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(header)
+
+	var configs []table.ColumnConfig
+	for i := len(header) - right + 1; i <= len(header); i++ {
+		configs = append(configs, table.ColumnConfig{Number: i, Align: text.AlignRight, AlignHeader: text.AlignRight, AlignFooter: text.AlignRight})
+	}
+	t.SetColumnConfigs(configs)
+
+	return t
+}
+
+// statusCommand describes the node and its latest block.
+func statusCommand(args []string) (string, []table.Writer, error) {
+	statusRPC, err := getResultFromRPC("status")
+	if err != nil {
+		return "", nil, err
+	}
+	status := gjson.Get(statusRPC, "result")
+
+	t := newTable(0, "STATUS", "")
+	t.AppendRows([]table.Row{
+		{"Network", status.Get("node_info.network").String()},
+		{"Moniker", status.Get("node_info.moniker").String()},
+		{"Node ID", status.Get("node_info.id").String()},
+		{"Version", status.Get("node_info.version").String()},
+		{"RPC", getHttpUrl()},
+		{"Latest Height", numberWithComma(status.Get("sync_info.latest_block_height").Int())},
+		{"Latest Block Time", formatTime(status.Get("sync_info.latest_block_time").String())},
+		{"Catching Up", status.Get("sync_info.catching_up").Bool()},
+		{"Validator", status.Get("validator_info.address").String()},
+		{"Voting Power", numberWithComma(status.Get("validator_info.voting_power").Int())},
+	})
+
+	return status.Raw, []table.Writer{t}, nil
+}
+
+// validatorsCommand lists the whole validator set of the latest block.
+func validatorsCommand(args []string) (string, []table.Writer, error) {
+	validators, err := queryValidatorSet(0)
+	if err != nil {
+		return "", nil, err
+	}
+
+	totalPower := int64(0)
+	var raws []string
+	for _, v := range validators {
+		totalPower += v.Get("voting_power").Int()
+		raws = append(raws, v.Raw)
+	}
+
+	t := newTable(3, "ADDRESS", "VOTING POWER", "SHARE", "PRIORITY")
+	for _, v := range validators {
+		power := v.Get("voting_power").Int()
+		share := 0.0
+		// don't divide by 0
+		if totalPower > 0 {
+			share = float64(power) / float64(totalPower) * 100
+		}
+		t.AppendRow(table.Row{
+			v.Get("address").String(),
+			numberWithComma(power),
+			fmt.Sprintf("%.2f%%", share),
+			numberWithComma(v.Get("proposer_priority").Int()),
+		})
+	}
+	t.AppendFooter(table.Row{fmt.Sprintf("%d validators", len(validators)), numberWithComma(totalPower), "", ""})
+
+	return "[" + strings.Join(raws, ",") + "]", []table.Writer{t}, nil
+}
+
+// peersCommand lists the connected peers.
+func peersCommand(args []string) (string, []table.Writer, error) {
+	netInfoRPC, err := getResultFromRPC("net_info")
+	if err != nil {
+		return "", nil, err
+	}
+	netInfo := gjson.Get(netInfoRPC, "result")
+
+	t := newTable(2, "MONIKER", "ID", "REMOTE IP", "DIR", "VERSION", "SEND", "RECV")
+	for _, peer := range netInfo.Get("peers").Array() {
+		direction := "in"
+		if peer.Get("is_outbound").Bool() {
+			direction = "out"
+		}
+		t.AppendRow(table.Row{
+			peer.Get("node_info.moniker").String(),
+			peer.Get("node_info.id").String(),
+			peer.Get("remote_ip").String(),
+			direction,
+			peer.Get("node_info.version").String(),
+			byteCountDecimal(peer.Get("connection_status.SendMonitor.AvgRate").Int()) + "/s",
+			byteCountDecimal(peer.Get("connection_status.RecvMonitor.AvgRate").Int()) + "/s",
+		})
+	}
+	t.AppendFooter(table.Row{netInfo.Get("n_peers").String() + " peers", "", "", "", "", "", ""})
+
+	return netInfo.Raw, []table.Writer{t}, nil
+}
+
+// blockCommand describes a block and the results of its transactions.
+func blockCommand(args []string) (string, []table.Writer, error) {
+	if !heightPattern.MatchString(args[0]) {
+		return "", nil, fmt.Errorf("%q is not a block height", args[0])
+	}
+	blockRPC, err := getResultFromRPC("block?height=" + args[0])
+	if err != nil {
+		return "", nil, err
+	}
+	result := gjson.Get(blockRPC, "result")
+	block := result.Get("block")
+	if !block.Exists() || block.Type == gjson.Null {
+		return "", nil, errors.New("block not found")
+	}
+	blockResultsRPC, _ := getFromRPC("block_results?height=" + args[0])
+	txResults := gjson.Get(blockResultsRPC, "result.txs_results").Array()
+	txs := block.Get("data.txs").Array()
+
+	t := newTable(0, "BLOCK", numberWithComma(block.Get("header.height").Int()))
+	t.AppendRows([]table.Row{
+		{"Hash", result.Get("block_id.hash").String()},
+		{"Chain", block.Get("header.chain_id").String()},
+		{"Time", formatTime(block.Get("header.time").String())},
+		{"Proposer", block.Get("header.proposer_address").String()},
+		{"App Hash", block.Get("header.app_hash").String()},
+		{"Signatures", len(block.Get("last_commit.signatures").Array())},
+		{"Transactions", len(txs)},
+	})
+	tables := []table.Writer{t}
+
+	if len(txs) > 0 {
+		txTable := newTable(2, "HASH", "RESULT", "GAS USED", "GAS WANTED")
+		for i, tx := range txs {
+			status, used, wanted := "", "", ""
+			if i < len(txResults) {
+				status = txResultStatus(txResults[i])
+				used = numberWithComma(txResults[i].Get("gas_used").Int())
+				wanted = numberWithComma(txResults[i].Get("gas_wanted").Int())
+			}
+			txTable.AppendRow(table.Row{txHash(tx.String()), status, used, wanted})
+		}
+		tables = append(tables, txTable)
+	}
+
+	return result.Raw, tables, nil
+}
+
+// txCommand describes a transaction and its events.
+func txCommand(args []string) (string, []table.Writer, error) {
+	if !hashPattern.MatchString(args[0]) {
+		return "", nil, fmt.Errorf("%q is not a transaction hash", args[0])
+	}
+	txRPC, err := getResultFromRPC("tx?hash=0x" + strings.TrimPrefix(args[0], "0x"))
+	if err != nil {
+		return "", nil, err
+	}
+	tx := gjson.Get(txRPC, "result")
+
+	messages, fee := "-", "-"
+	if decoded, err := decodeTx(tx.Get("tx").String()); err == nil {
+		messages = strings.Join(decoded.messages, "\n")
+		amounts := map[string]string{}
+		for _, coin := range decoded.fee {
+			amounts[coin.denom] = addAmounts(amounts[coin.denom], coin.amount)
+		}
+		fee = formatFees(amounts)
+	}
+
+	t := newTable(0, "TRANSACTION", tx.Get("hash").String())
+	t.AppendRows([]table.Row{
+		{"Height", numberWithComma(tx.Get("height").Int())},
+		{"Index", tx.Get("index").Int()},
+		{"Result", txResultStatus(tx.Get("tx_result"))},
+		{"Gas", fmt.Sprintf("%s / %s",
+			numberWithComma(tx.Get("tx_result.gas_used").Int()),
+			numberWithComma(tx.Get("tx_result.gas_wanted").Int()),
+		)},
+		{"Messages", messages},
+		{"Fee", fee},
+		{"Log", tx.Get("tx_result.log").String()},
+	})
+
+	events := newTable(0, "EVENT", "ATTRIBUTE", "VALUE")
+	for _, event := range tx.Get("tx_result.events").Array() {
+		for _, attr := range event.Get("attributes").Array() {
+			events.AppendRow(table.Row{
+				event.Get("type").String(),
+				eventAttribute(attr.Get("key").String()),
+				humanizeCoins(eventAttribute(attr.Get("value").String())),
+			})
+		}
+	}
+
+	return tx.Raw, []table.Writer{t, events}, nil
+}
+
+// paramsCommand lists the consensus parameters.
+func paramsCommand(args []string) (string, []table.Writer, error) {
+	paramsRPC, err := getResultFromRPC("consensus_params")
+	if err != nil {
+		return "", nil, err
+	}
+	result := gjson.Get(paramsRPC, "result")
+
+	t := newTable(0, "CONSENSUS PARAMS", "HEIGHT "+numberWithComma(result.Get("block_height").Int()))
+	flattenParams(t, "", result.Get("consensus_params"))
+
+	return result.Raw, []table.Writer{t}, nil
+}
+
+// flattenParams appends the parameters of nested objects as dotted keys.
+func flattenParams(t table.Writer, prefix string, params gjson.Result) {
+	params.ForEach(func(key, value gjson.Result) bool {
+		name := prefix + key.String()
+		if value.IsObject() {
+			flattenParams(t, name+".", value)
+			return true
+		}
+		if value.IsArray() {
+			var values []string
+			for _, v := range value.Array() {
+				values = append(values, v.String())
+			}
+			t.AppendRow(table.Row{name, strings.Join(values, ", ")})
+			return true
+		}
+		t.AppendRow(table.Row{name, value.String()})
+		return true
+	})
+}
+
+// txResultStatus describes the result code of a transaction.
+func txResultStatus(result gjson.Result) string {
+	code := result.Get("code").Int()
+	if code == 0 {
+		return "ok"
+	}
+
+	return txError(result.Get("codespace").String(), code)
+}
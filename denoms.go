@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// denomRefresh is how often the denom metadata of x/bank is queried.
+const denomRefresh = 10 * time.Minute
+
+// coinPattern matches a raw amount of a base denom, e.g. 1000000uatom.
+var coinPattern = regexp.MustCompile(`\b(\d+(?:\.\d+)?)([a-zA-Z][a-zA-Z0-9/:._-]{2,127})\b`)
+
+// denomUnit is how the amounts of a base denom are displayed.
+type denomUnit struct {
+	symbol   string
+	exponent int
+}
+
+// denomRegistry caches the display units of the base denoms.
+type denomRegistry struct {
+	mu      sync.Mutex
+	units   map[string]denomUnit
+	loaded  time.Time
+	loading bool
+}
+
+// denoms are the display units of the chain, loaded from x/bank and the -d flag.
+var denoms denomRegistry
+
+// unit returns the display unit of a base denom. The units are loaded the
+// first time and refreshed in the background, the lock isn't held while the
+// API is queried.
+func (d *denomRegistry) unit(denom string) (denomUnit, bool) {
+	d.mu.Lock()
+	stale := !d.loading && time.Since(d.loaded) > denomRefresh
+	first := d.units == nil
+	if stale {
+		d.loading = true
+	}
+	d.mu.Unlock()
+
+	if stale && first {
+		d.load()
+	} else if stale {
+		go d.load()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	u, ok := d.units[denom]
+
+	return u, ok
+}
+
+// load queries the units and swaps them in.
+func (d *denomRegistry) load() {
+	units := loadDenomUnits()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.units, d.loaded, d.loading = units, time.Now(), false
+}
+
+// loadDenomUnits queries the denom metadata of x/bank, the units given with
+// the -d flag take precedence.
+func loadDenomUnits() map[string]denomUnit {
+	units := map[string]denomUnit{}
+
+	metadataRPC, err := getFromAPI("/cosmos/bank/v1beta1/denoms_metadata?pagination.limit=1000")
+	if err == nil {
+		for _, m := range gjson.Get(metadataRPC, "metadatas").Array() {
+			display := m.Get("display").String()
+			symbol := m.Get("symbol").String()
+			if symbol == "" {
+				symbol = strings.ToUpper(display)
+			}
+			for _, u := range m.Get("denom_units").Array() {
+				if u.Get("denom").String() == display && u.Get("exponent").Int() > 0 {
+					units[m.Get("base").String()] = denomUnit{symbol: symbol, exponent: int(u.Get("exponent").Int())}
+				}
+			}
+		}
+	}
+
+	given, _ := parseDenomUnits(*givenDenoms)
+	for denom, u := range given {
+		units[denom] = u
+	}
+
+	return units
+}
+
+// parseDenomUnits parses the display units of the -d flag,
+// e.g. uatom=ATOM:6,aevmos=EVMOS:18.
+func parseDenomUnits(s string) (map[string]denomUnit, error) {
+	units := map[string]denomUnit{}
+	if s == "" {
+		return units, nil
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		parts := strings.FieldsFunc(strings.TrimSpace(entry), func(r rune) bool { return r == '=' || r == ':' })
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid denom unit %q, expected <denom>=<symbol>:<exponent>", entry)
+		}
+		denom, symbol := parts[0], parts[1]
+		e, err := strconv.Atoi(parts[2])
+		if err != nil || e < 0 {
+			return nil, fmt.Errorf("invalid exponent of denom unit %q", entry)
+		}
+		units[denom] = denomUnit{symbol: symbol, exponent: e}
+	}
+
+	return units, nil
+}
+
+// formatCoin formats an amount of a base denom in its display unit, e.g.
+// 1000000uatom becomes 1 ATOM. Denoms without metadata keep their raw amount.
+func formatCoin(amount, denom string) string {
+	u, ok := denoms.unit(denom)
+	if !ok {
+		return formatAmount(amount) + denom
+	}
+
+	return formatAmount(shiftDecimal(amount, u.exponent)) + " " + u.symbol
+}
+
+// formatDisplayAmount formats an amount of a base denom in its display unit
+// without the symbol.
+func formatDisplayAmount(amount, denom string) string {
+	u, ok := denoms.unit(denom)
+	if !ok {
+		return formatAmount(amount)
+	}
+
+	return formatAmount(shiftDecimal(amount, u.exponent))
+}
+
+// displaySymbol returns the symbol of the display unit of a base denom.
+func displaySymbol(denom string) string {
+	if u, ok := denoms.unit(denom); ok {
+		return u.symbol
+	}
+
+	return denom
+}
+
+// humanizeCoins formats the raw amounts of the denoms with metadata found in
+// logs and event attributes, e.g. 1000000uatom,5stake.
+func humanizeCoins(s string) string {
+	return coinPattern.ReplaceAllStringFunc(s, func(coin string) string {
+		m := coinPattern.FindStringSubmatch(coin)
+		if _, ok := denoms.unit(m[2]); !ok {
+			return coin
+		}
+		return formatCoin(m[1], m[2])
+	})
+}
+
+// shiftDecimal divides a decimal amount by 10^exponent without losing
+// precision.
+func shiftDecimal(amount string, exponent int) string {
+	integer, fraction := amount, ""
+	if i := strings.Index(amount, "."); i >= 0 {
+		integer, fraction = amount[:i], amount[i+1:]
+	}
+	digits := strings.TrimLeft(integer, "0") + fraction
+	point := len(strings.TrimLeft(integer, "0")) - exponent
+
+	if point <= 0 {
+		return "0." + strings.Repeat("0", -point) + digits
+	}
+	if point >= len(digits) {
+		return digits + strings.Repeat("0", point-len(digits))
+	}
+
+	return digits[:point] + "." + digits[point:]
+}
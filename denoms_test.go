@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestShiftDecimal(t *testing.T) {
+	tests := []struct {
+		amount   string
+		exponent int
+		want     string
+	}{
+		{"1000000", 6, "1.000000"},
+		{"1234567", 6, "1.234567"},
+		{"1", 6, "0.000001"},
+		{"123", 0, "123"},
+		{"0", 6, "0.000000"},
+		{"000123", 2, "1.23"},
+		{"1.5", 2, "0.015"},
+		{"123.45", 1, "12.345"},
+		{"1000000000000000000000", 18, "1000.000000000000000000"},
+		{"5", 1, "0.5"},
+	}
+
+	for _, tt := range tests {
+		if got := shiftDecimal(tt.amount, tt.exponent); got != tt.want {
+			t.Errorf("shiftDecimal(%q, %d) = %q, want %q", tt.amount, tt.exponent, got, tt.want)
+		}
+	}
+}
+
+func TestParseDenomUnits(t *testing.T) {
+	tests := []struct {
+		input string
+		units map[string]denomUnit
+		valid bool
+	}{
+		{"", map[string]denomUnit{}, true},
+		{"uatom=ATOM:6", map[string]denomUnit{"uatom": {"ATOM", 6}}, true},
+		{"uatom=ATOM:6, aevmos=EVMOS:18", map[string]denomUnit{"uatom": {"ATOM", 6}, "aevmos": {"EVMOS", 18}}, true},
+		{"uatom=ATOM", nil, false},
+		{"uatom=ATOM:x", nil, false},
+		{"uatom=ATOM:-1", nil, false},
+	}
+
+	for _, tt := range tests {
+		units, err := parseDenomUnits(tt.input)
+		if (err == nil) != tt.valid {
+			t.Errorf("parseDenomUnits(%q) error = %v, want valid %v", tt.input, err, tt.valid)
+			continue
+		}
+		if len(units) != len(tt.units) {
+			t.Errorf("parseDenomUnits(%q) = %v, want %v", tt.input, units, tt.units)
+			continue
+		}
+		for denom, u := range tt.units {
+			if units[denom] != u {
+				t.Errorf("parseDenomUnits(%q)[%q] = %v, want %v", tt.input, denom, units[denom], u)
+			}
+		}
+	}
+}
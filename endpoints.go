@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"gopkg.in/resty.v1"
+)
+
+// endpointTimeout bounds the probe of an RPC endpoint.
+const endpointTimeout = 3 * time.Second
+
+// heightTolerance is how many blocks the active endpoint may fall behind the
+// highest endpoint before gex fails over.
+const heightTolerance = 2
+
+// rpcEndpoint is an RPC of the chain and its websocket.
+type rpcEndpoint struct {
+	url  string
+	ws   string
+	auth string
+}
+
+// endpointProbe is the state of an RPC endpoint at the last probe.
+type endpointProbe struct {
+	healthy bool
+	height  int64
+	latency time.Duration
+}
+
+// endpointPool holds the RPC endpoints given for the chain and the one gex is
+// connected to.
+type endpointPool struct {
+	mu     sync.Mutex
+	list   []rpcEndpoint
+	probes []endpointProbe
+	active int
+	client *resty.Client
+}
+
+// endpoints are the RPC endpoints of the chain, set up from the flags.
+var endpoints endpointPool
+
+// current returns the active endpoint.
+func (p *endpointPool) current() rpcEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.list[p.active]
+}
+
+// healthy returns the number of endpoints that were healthy at the last probe.
+func (p *endpointPool) healthy() (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for _, probe := range p.probes {
+		if probe.healthy {
+			n++
+		}
+	}
+
+	return n, len(p.list)
+}
+
+// failover probes all endpoints and switches to the best one when the active
+// endpoint is down, catching up or behind. Returns whether it switched.
+func (p *endpointPool) failover() bool {
+	p.mu.Lock()
+	list := p.list
+	if p.client == nil {
+		p.client = resty.New().SetTimeout(endpointTimeout).SetTransport(newTransport())
+	}
+	client := p.client
+	p.mu.Unlock()
+
+	if len(list) < 2 {
+		return false
+	}
+
+	probes := make([]endpointProbe, len(list))
+	var wg sync.WaitGroup
+	for i, e := range list {
+		wg.Add(1)
+		go func(i int, e rpcEndpoint) {
+			defer wg.Done()
+			probes[i] = probeEndpoint(client, e)
+		}(i, e)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.probes = probes
+	best := chooseEndpoint(probes, p.active)
+	if best == p.active {
+		return false
+	}
+	p.active = best
+
+	return true
+}
+
+// probeEndpoint checks the health and the sync status of an endpoint.
+func probeEndpoint(client *resty.Client, e rpcEndpoint) endpointProbe {
+	if _, err := getFromEndpoint(client, e, "health"); err != nil {
+		return endpointProbe{}
+	}
+
+	start := time.Now()
+	statusRPC, err := getFromEndpoint(client, e, "status")
+	latency := time.Since(start)
+	syncInfo := gjson.Get(statusRPC, "result.sync_info")
+	if err != nil || !syncInfo.Exists() {
+		return endpointProbe{}
+	}
+
+	return endpointProbe{
+		healthy: !syncInfo.Get("catching_up").Bool(),
+		height:  syncInfo.Get("latest_block_height").Int(),
+		latency: latency,
+	}
+}
+
+// chooseEndpoint keeps the active endpoint while it is healthy and close to
+// the highest block, otherwise it picks the healthy endpoint with the lowest
+// latency among those at the highest block.
+func chooseEndpoint(probes []endpointProbe, active int) int {
+	var maxHeight int64 = -1
+	for _, probe := range probes {
+		if probe.healthy && probe.height > maxHeight {
+			maxHeight = probe.height
+		}
+	}
+	// no endpoint is healthy, wait for the active one to recover
+	if maxHeight < 0 {
+		return active
+	}
+	if probes[active].healthy && probes[active].height >= maxHeight-heightTolerance {
+		return active
+	}
+
+	best := active
+	for i, probe := range probes {
+		if !probe.healthy || probe.height < maxHeight-heightTolerance {
+			continue
+		}
+		if best == active || probe.latency < probes[best].latency {
+			best = i
+		}
+	}
+
+	return best
+}
+
+// writeNetwork writes the network and the active endpoint to the networkWidget
+// and fails over to another endpoint when needed.
+// Exits when the context expires.
+func writeNetwork(ctx context.Context, t textWidget, network string, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		t.Reset()
+		if err := t.Write(network + "\n" + endpointHost(endpoints.current().url)); err != nil {
+			panic(err)
+		}
+		if healthy, total := endpoints.healthy(); total > 1 {
+			if err := t.Write(fmt.Sprintf("\n%d/%d up", healthy, total)); err != nil {
+				panic(err)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+			endpoints.failover()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// endpointHost returns the host of an endpoint URL, with its path prefix.
+func endpointHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+
+	return u.Host + u.Path
+}
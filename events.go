@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// pollInterval is how often the RPC is polled for new blocks when the
+// websocket is unavailable.
+const pollInterval = 1 * time.Second
+
+// pollCatchUp is the most blocks a poll fetches, older blocks are skipped.
+const pollCatchUp = 10
+
+// blockQuery is the subscription query of new blocks.
+const blockQuery = "tm.event='NewBlock'"
+
+// roundStepQuery is the subscription query of the consensus round steps.
+const roundStepQuery = "tm.event='NewRoundStep'"
+
+// eventSource delivers the events of subscriptions as websocket messages,
+// whether they come from the websocket or from polling the RPC.
+type eventSource interface {
+	subscribe(query string, id int)
+	unsubscribe(query string, id int)
+	close()
+}
+
+// eventModes tracks whether the open event sources get the events from the
+// websocket or by polling.
+var eventModes struct {
+	mu      sync.Mutex
+	polling map[eventSource]bool
+}
+
+// setEventMode records whether a source gets the events by polling.
+func setEventMode(source eventSource, polling bool) {
+	eventModes.mu.Lock()
+	defer eventModes.mu.Unlock()
+
+	if eventModes.polling == nil {
+		eventModes.polling = map[eventSource]bool{}
+	}
+	eventModes.polling[source] = polling
+}
+
+// forgetEventMode drops a closed source.
+func forgetEventMode(source eventSource) {
+	eventModes.mu.Lock()
+	defer eventModes.mu.Unlock()
+
+	delete(eventModes.polling, source)
+}
+
+// sourcePolling returns whether a source gets the events by polling.
+func sourcePolling(source eventSource) bool {
+	eventModes.mu.Lock()
+	defer eventModes.mu.Unlock()
+
+	return eventModes.polling[source]
+}
+
+// eventMode describes where the events come from.
+func eventMode() string {
+	if eventPolling() {
+		return "⟳ polling"
+	}
+
+	return "⇄ websocket"
+}
+
+// eventPolling returns whether any source gets the events by polling the RPC.
+func eventPolling() bool {
+	eventModes.mu.Lock()
+	defer eventModes.mu.Unlock()
+
+	for _, polling := range eventModes.polling {
+		if polling {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newEventSource connects to the websocket of the active endpoint and falls
+// back to polling the RPC when the websocket is unavailable.
+func newEventSource(onEvent func(message string)) eventSource {
+	source := &websocketSource{onEvent: onEvent, done: make(chan struct{})}
+	if !source.connect() {
+		polling := &pollingSource{onEvent: onEvent}
+		setEventMode(polling, true)
+		return polling
+	}
+	setEventMode(source, false)
+	go source.keepalive()
+
+	return source
+}
+
+// pollingSource receives the events the blockPoller synthesizes.
+type pollingSource struct {
+	onEvent func(message string)
+}
+
+func (s *pollingSource) subscribe(query string, id int) {
+	poller.add(subscription{source: s, query: query, id: id})
+}
+
+func (s *pollingSource) unsubscribe(query string, id int) {
+	poller.remove(func(sub subscription) bool {
+		return sub.source == s && sub.query == query && sub.id == id
+	})
+}
+
+func (s *pollingSource) close() {
+	forgetEventMode(s)
+	poller.remove(func(sub subscription) bool {
+		return sub.source == s
+	})
+}
+
+// subscription is a query polled for a source.
+type subscription struct {
+	source *pollingSource
+	query  string
+	id     int
+}
+
+// blockPoller fetches the new blocks with status, block and block_results
+// and synthesizes the events of the subscriptions. It polls while there are
+// subscriptions.
+type blockPoller struct {
+	mu      sync.Mutex
+	subs    []subscription
+	running bool
+	height  int64
+}
+
+// poller serves the polling sources.
+var poller blockPoller
+
+// add adds a subscription and starts polling.
+func (p *blockPoller) add(sub subscription) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.subs = append(p.subs, sub)
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+}
+
+// remove removes the matching subscriptions.
+func (p *blockPoller) remove(match func(sub subscription) bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	subs := p.subs[:0]
+	for _, sub := range p.subs {
+		if !match(sub) {
+			subs = append(subs, sub)
+		}
+	}
+	p.subs = subs
+}
+
+// run polls until no subscriptions are left.
+func (p *blockPoller) run() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.mu.Lock()
+		if len(p.subs) == 0 {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		p.poll()
+	}
+}
+
+// poll fetches the blocks since the last poll and delivers their events.
+func (p *blockPoller) poll() {
+	statusRPC, err := getFromRPC("status")
+	if err != nil {
+		return
+	}
+	latest := gjson.Get(statusRPC, "result.sync_info.latest_block_height").Int()
+
+	p.mu.Lock()
+	from := p.height + 1
+	if p.height == 0 || latest-p.height > pollCatchUp {
+		from = latest
+	}
+	p.mu.Unlock()
+
+	// the chain is proposing the next block until a new one is committed
+	if from > latest {
+		p.deliver(roundStepQuery, roundStepEvent("RoundStepPropose"))
+		return
+	}
+
+	for height := from; height <= latest; height++ {
+		if err := p.pollBlock(height); err != nil {
+			return
+		}
+		p.mu.Lock()
+		p.height = height
+		p.mu.Unlock()
+	}
+}
+
+// pollBlock delivers the events of a block.
+func (p *blockPoller) pollBlock(height int64) error {
+	blockRPC, err := getFromRPC(fmt.Sprintf("block?height=%d", height))
+	if err != nil {
+		return err
+	}
+	resultsRPC, err := getFromRPC(fmt.Sprintf("block_results?height=%d", height))
+	if err != nil {
+		return err
+	}
+	block := gjson.Get(blockRPC, "result.block")
+	results := gjson.Get(resultsRPC, "result")
+	if !block.Exists() || !results.Exists() {
+		return fmt.Errorf("block %d not found", height)
+	}
+
+	p.deliver(blockQuery, newBlockEvent(block, results))
+	p.deliver(roundStepQuery, roundStepEvent("RoundStepNewHeight"))
+
+	txResults := results.Get("txs_results").Array()
+	for i, tx := range block.Get("data.txs").Array() {
+		if i >= len(txResults) {
+			break
+		}
+		p.deliver(txQuery, txEvent(txQuery, height, i, tx.String(), txHash(tx.String()), txResults[i]))
+	}
+
+	p.mu.Lock()
+	var filtered []subscription
+	for _, sub := range p.subs {
+		if strings.HasPrefix(sub.query, txQuery+" AND ") {
+			filtered = append(filtered, sub)
+		}
+	}
+	p.mu.Unlock()
+
+	// the node evaluates the filters of the transactions
+	for _, sub := range filtered {
+		query := fmt.Sprintf("tx.height=%d AND %s", height, strings.TrimPrefix(sub.query, txQuery+" AND "))
+		searchRPC, err := getFromRPC("tx_search?per_page=100&query=" + url.QueryEscape(`"`+query+`"`))
+		if err != nil {
+			continue
+		}
+		// a rejected filter is reported once, like the websocket does
+		if rpcError := gjson.Get(searchRPC, "error"); rpcError.Exists() {
+			p.remove(func(s subscription) bool { return s == sub })
+			sub.source.onEvent(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":%s}`, sub.id, rpcError.Raw))
+			continue
+		}
+		for _, tx := range gjson.Get(searchRPC, "result.txs").Array() {
+			sub.source.onEvent(txEvent(sub.query, height, int(tx.Get("index").Int()), tx.Get("tx").String(), tx.Get("hash").String(), tx.Get("tx_result")))
+		}
+	}
+
+	return nil
+}
+
+// deliver delivers an event to the subscriptions of the query.
+func (p *blockPoller) deliver(query string, message string) {
+	p.mu.Lock()
+	var sources []*pollingSource
+	for _, sub := range p.subs {
+		if sub.query == query {
+			sources = append(sources, sub.source)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, source := range sources {
+		source.onEvent(message)
+	}
+}
+
+// txEvent synthesizes the Tx event of a transaction with its events indexed
+// like the websocket does.
+func txEvent(query string, height int64, index int, tx string, hash string, result gjson.Result) string {
+	events := map[string][]string{
+		"tm.event":  {"Tx"},
+		"tx.hash":   {hash},
+		"tx.height": {fmt.Sprint(height)},
+	}
+	for _, event := range result.Get("events").Array() {
+		for _, attribute := range event.Get("attributes").Array() {
+			key, value := eventAttribute(attribute.Get("key").String()), eventAttribute(attribute.Get("value").String())
+			name := event.Get("type").String() + "." + key
+			events[name] = append(events[name], value)
+		}
+	}
+
+	return eventMessage(query, "tendermint/event/Tx", map[string]interface{}{
+		"TxResult": map[string]interface{}{
+			"height": fmt.Sprint(height),
+			"index":  index,
+			"tx":     tx,
+			"result": json.RawMessage(result.Raw),
+		},
+	}, events)
+}
+
+// newBlockEvent synthesizes the NewBlock event of a block.
+func newBlockEvent(block gjson.Result, results gjson.Result) string {
+	endBlock := map[string]interface{}{}
+	if updates := results.Get("consensus_param_updates"); updates.Exists() && updates.Type != gjson.Null {
+		endBlock["consensus_param_updates"] = json.RawMessage(updates.Raw)
+	}
+
+	return eventMessage(blockQuery, "tendermint/event/NewBlock", map[string]interface{}{
+		"block":            json.RawMessage(block.Raw),
+		"result_end_block": endBlock,
+	}, map[string][]string{"tm.event": {"NewBlock"}})
+}
+
+// roundStepEvent synthesizes a NewRoundStep event.
+func roundStepEvent(step string) string {
+	return eventMessage(roundStepQuery, "tendermint/event/RoundState", map[string]interface{}{
+		"step": step,
+	}, map[string][]string{"tm.event": {"NewRoundStep"}})
+}
+
+// eventMessage encodes an event like the websocket sends it.
+func eventMessage(query string, eventType string, value interface{}, events map[string][]string) string {
+	message, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"result": map[string]interface{}{
+			"query": query,
+			"data": map[string]interface{}{
+				"type":  eventType,
+				"value": value,
+			},
+			"events": events,
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return string(message)
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestEventPolling(t *testing.T) {
+	websocket, polling, other := &websocketSource{}, &pollingSource{}, &websocketSource{}
+	defer forgetEventMode(websocket)
+	defer forgetEventMode(polling)
+	defer forgetEventMode(other)
+
+	setEventMode(websocket, false)
+	if eventPolling() {
+		t.Error("eventPolling() = true with only a websocket source")
+	}
+
+	// another source falling back doesn't hide the polling one, nor the other way round
+	setEventMode(polling, true)
+	setEventMode(other, false)
+	if !eventPolling() {
+		t.Error("eventPolling() = false with a polling source")
+	}
+
+	forgetEventMode(polling)
+	if eventPolling() {
+		t.Error("eventPolling() = true after the polling source closed")
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// feeWindow is the time span the gas price statistics cover.
+const feeWindow = 10 * time.Minute
+
+// feeBlocks is the number of recent blocks the fee table lists.
+const feeBlocks = 20
+
+// minGasPriceRefresh is how often the minimum gas price of the node is queried.
+const minGasPriceRefresh = 1 * time.Minute
+
+// Fees tracks the fees paid by the confirmed transactions
+type Fees struct {
+	mu     sync.Mutex
+	window []gasPriceSeen
+	blocks []blockFees
+}
+
+// gasPriceSeen is the gas price a transaction paid in a denom
+type gasPriceSeen struct {
+	at    time.Time
+	denom string
+	price float64
+}
+
+// blockFees are the fees paid by the transactions of a block
+type blockFees struct {
+	height  int64
+	txs     int
+	amounts map[string]string
+}
+
+// gasPriceStats are the gas prices paid in a denom.
+type gasPriceStats struct {
+	denom  string
+	txs    int
+	min    float64
+	median float64
+	max    float64
+}
+
+// count counts the fee of a Tx event received over the websocket.
+func (f *Fees) count(message string) {
+	txResult := gjson.Get(message, "result.data.value.TxResult")
+	tx, err := decodeTx(txResult.Get("tx").String())
+	if err != nil {
+		return
+	}
+	gasLimit := float64(tx.gasLimit)
+	if gasLimit == 0 {
+		gasLimit = txResult.Get("result.gas_wanted").Float()
+	}
+	height := txResult.Get("height").Int()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.blocks) == 0 || f.blocks[len(f.blocks)-1].height != height {
+		f.blocks = append(f.blocks, blockFees{height: height, amounts: map[string]string{}})
+		if len(f.blocks) > feeBlocks {
+			f.blocks = f.blocks[1:]
+		}
+	}
+	b := &f.blocks[len(f.blocks)-1]
+	b.txs++
+
+	now := time.Now()
+	for _, coin := range tx.fee {
+		b.amounts[coin.denom] = addAmounts(b.amounts[coin.denom], coin.amount)
+
+		amount, err := strconv.ParseFloat(coin.amount, 64)
+		// don't divide by 0
+		if err != nil || gasLimit == 0 {
+			continue
+		}
+		f.window = append(f.window, gasPriceSeen{at: now, denom: coin.denom, price: amount / gasLimit})
+	}
+}
+
+// stats returns the gas prices paid per denom in the window and the fees of
+// the recent blocks with transactions, the latest first.
+func (f *Fees) stats() ([]gasPriceStats, []blockFees) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// drop the prices that left the window
+	cutoff := time.Now().Add(-feeWindow)
+	i := 0
+	for i < len(f.window) && f.window[i].at.Before(cutoff) {
+		i++
+	}
+	f.window = f.window[i:]
+
+	prices := map[string][]float64{}
+	for _, seen := range f.window {
+		prices[seen.denom] = append(prices[seen.denom], seen.price)
+	}
+
+	var stats []gasPriceStats
+	for denom, p := range prices {
+		sort.Float64s(p)
+		stats = append(stats, gasPriceStats{
+			denom:  denom,
+			txs:    len(p),
+			min:    p[0],
+			median: median(p),
+			max:    p[len(p)-1],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].txs != stats[j].txs {
+			return stats[i].txs > stats[j].txs
+		}
+		return stats[i].denom < stats[j].denom
+	})
+
+	// copy the amounts, count keeps adding to the ones of the latest block
+	blocks := make([]blockFees, len(f.blocks))
+	for i, b := range f.blocks {
+		amounts := make(map[string]string, len(b.amounts))
+		for denom, amount := range b.amounts {
+			amounts[denom] = amount
+		}
+		b.amounts = amounts
+		blocks[len(f.blocks)-1-i] = b
+	}
+
+	return stats, blocks
+}
+
+// median returns the median of sorted values.
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// feesPage creates the page with the gas prices and the fees paid.
+func feesPage(ctx context.Context, info Info, delay time.Duration) page {
+	gasPricesWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := gasPricesWidget.Write("Gas prices will appear as soon as transactions are confirmed in a block.\n"); err != nil {
+		panic(err)
+	}
+
+	blockFeesWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+
+	go writeFees(ctx, info, gasPricesWidget, blockFeesWidget, delay)
+
+	return page{
+		name: "Fees",
+		layout: []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.Border(linestyle.Light),
+					container.BorderTitle(fmt.Sprintf("Gas Prices (last %d minutes)", int(feeWindow.Minutes()))),
+					container.PlaceWidget(gasPricesWidget),
+				),
+				container.Bottom(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Fees per Block (blocks with transactions since gex started)"),
+					container.PlaceWidget(blockFeesWidget),
+				),
+				container.SplitPercent(40),
+			),
+		},
+	}
+}
+
+// writeFees writes the gas prices per denom and the fees of the recent blocks.
+// Exits when the context expires.
+func writeFees(ctx context.Context, info Info, tPrices *text.Text, tBlocks *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	minGasPrice := ""
+	var minGasPriceQueried time.Time
+
+	for {
+		select {
+		case <-ticker.C:
+			if time.Since(minGasPriceQueried) > minGasPriceRefresh {
+				minGasPrice = queryMinGasPrice()
+				minGasPriceQueried = time.Now()
+			}
+
+			stats, blocks := info.fees.stats()
+			if len(blocks) == 0 {
+				continue
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "Minimum Gas Price of the node: %s\n\n", minGasPrice)
+			fmt.Fprintf(&b, "%-20s %6s %16s %16s %16s\n", "DENOM", "TXS", "MIN", "MEDIAN", "MAX")
+			for _, s := range stats {
+				fmt.Fprintf(&b, "%-20s %6d %16s %16s %16s\n",
+					shorten(s.denom, 20),
+					s.txs,
+					formatGasPrice(s.min),
+					formatGasPrice(s.median),
+					formatGasPrice(s.max),
+				)
+			}
+
+			tPrices.Reset()
+			if err := tPrices.Write(b.String()); err != nil {
+				panic(err)
+			}
+
+			b.Reset()
+			fmt.Fprintf(&b, "%-12s %5s  %s\n", "HEIGHT", "TXS", "FEES")
+			for _, block := range blocks {
+				fmt.Fprintf(&b, "%-12s %5d  %s\n", numberWithComma(block.height), block.txs, formatFees(block.amounts))
+			}
+
+			tBlocks.Reset()
+			if err := tBlocks.Write(b.String()); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// queryMinGasPrice queries the minimum gas price configured in the app.toml
+// of the node, it's "-" if the node doesn't expose it.
+func queryMinGasPrice() string {
+	configRPC, err := getFromAPI("/cosmos/base/node/v1beta1/config")
+	if err != nil {
+		return "-"
+	}
+	minGasPrice := gjson.Get(configRPC, "minimum_gas_price").String()
+	if minGasPrice == "" {
+		return "none"
+	}
+
+	return minGasPrice
+}
+
+// formatGasPrice formats a gas price with up to 6 decimals.
+func formatGasPrice(price float64) string {
+	return formatAmount(strconv.FormatFloat(price, 'f', 6, 64))
+}
+
+// formatFees formats the fee amounts of a block, sorted by denom.
+func formatFees(amounts map[string]string) string {
+	var sorted []string
+	for denom := range amounts {
+		sorted = append(sorted, denom)
+	}
+	sort.Strings(sorted)
+
+	var formatted []string
+	for _, denom := range sorted {
+		formatted = append(formatted, formatCoin(amounts[denom], denom))
+	}
+	if len(formatted) == 0 {
+		return "-"
+	}
+
+	return strings.Join(formatted, ", ")
+}
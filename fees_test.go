@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// encodeTx encodes a TxRaw with the messages, the fee and the gas limit.
+func encodeTx(messages []string, fee []txCoin, gasLimit uint64) string {
+	var body []byte
+	for _, m := range messages {
+		body = protoAppendMessage(body, 1, protoAppendBytes(nil, 1, []byte(m)))
+	}
+
+	var feeMsg []byte
+	for _, c := range fee {
+		coin := protoAppendBytes(nil, 1, []byte(c.denom))
+		coin = protoAppendBytes(coin, 2, []byte(c.amount))
+		feeMsg = protoAppendMessage(feeMsg, 1, coin)
+	}
+	feeMsg = protoAppendVarint(feeMsg, 2, gasLimit)
+
+	raw := protoAppendBytes(nil, 1, body)
+	raw = protoAppendMessage(raw, 2, protoAppendMessage(nil, 2, feeMsg))
+
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// feeEvent returns the Tx event of a transaction in a block.
+func feeEvent(height int64, tx string, gasWanted int64) string {
+	return txEvent(txQuery, height, 0, tx, "", gjson.Parse(fmt.Sprintf(`{"gas_wanted":"%d"}`, gasWanted)))
+}
+
+func TestDecodeTx(t *testing.T) {
+	send := "/cosmos.bank.v1beta1.MsgSend"
+	delegate := "/cosmos.staking.v1beta1.MsgDelegate"
+
+	tests := []struct {
+		name  string
+		tx    string
+		want  decodedTx
+		valid bool
+	}{
+		{
+			"one message and fee",
+			encodeTx([]string{send}, []txCoin{{"uatom", "5000"}}, 200000),
+			decodedTx{messages: []string{send}, fee: []txCoin{{"uatom", "5000"}}, gasLimit: 200000},
+			true,
+		},
+		{
+			"fee in several denoms",
+			encodeTx([]string{send, delegate}, []txCoin{{"uatom", "5000"}, {"ibc/27394FB0", "12"}}, 300000),
+			decodedTx{messages: []string{send, delegate}, fee: []txCoin{{"uatom", "5000"}, {"ibc/27394FB0", "12"}}, gasLimit: 300000},
+			true,
+		},
+		{
+			"no fee",
+			encodeTx([]string{send}, nil, 100000),
+			decodedTx{messages: []string{send}, gasLimit: 100000},
+			true,
+		},
+		{"not base64", "not base64!", decodedTx{}, false},
+		{"truncated", base64.StdEncoding.EncodeToString([]byte{0x0a, 0x10, 0x0a}), decodedTx{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeTx(tt.tx)
+			if (err == nil) != tt.valid {
+				t.Fatalf("decodeTx() error = %v, want valid %v", err, tt.valid)
+			}
+			if tt.valid && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeTx() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeesStats(t *testing.T) {
+	type tx struct {
+		height    int64
+		fee       []txCoin
+		gasLimit  uint64
+		gasWanted int64
+	}
+
+	tests := []struct {
+		name string
+		// expired are prices seen before the window
+		expired []gasPriceSeen
+		txs     []tx
+		want    []gasPriceStats
+	}{
+		{
+			name: "one transaction",
+			txs:  []tx{{1, []txCoin{{"uatom", "5000"}}, 200000, 0}},
+			want: []gasPriceStats{{denom: "uatom", txs: 1, min: 0.025, median: 0.025, max: 0.025}},
+		},
+		{
+			name: "odd number of transactions",
+			txs: []tx{
+				{1, []txCoin{{"uatom", "3000"}}, 100000, 0},
+				{1, []txCoin{{"uatom", "1000"}}, 100000, 0},
+				{2, []txCoin{{"uatom", "2000"}}, 100000, 0},
+			},
+			want: []gasPriceStats{{denom: "uatom", txs: 3, min: 0.01, median: 0.02, max: 0.03}},
+		},
+		{
+			name: "even number of transactions",
+			txs: []tx{
+				{1, []txCoin{{"uatom", "1000"}}, 100000, 0},
+				{1, []txCoin{{"uatom", "4000"}}, 100000, 0},
+				{1, []txCoin{{"uatom", "2000"}}, 100000, 0},
+				{1, []txCoin{{"uatom", "3000"}}, 100000, 0},
+			},
+			want: []gasPriceStats{{denom: "uatom", txs: 4, min: 0.01, median: 0.025, max: 0.04}},
+		},
+		{
+			name: "denoms sorted by transactions",
+			txs: []tx{
+				{1, []txCoin{{"uosmo", "100"}}, 100000, 0},
+				{1, []txCoin{{"uatom", "1000"}, {"uosmo", "300"}}, 100000, 0},
+				{2, []txCoin{{"ustars", "500"}}, 100000, 0},
+			},
+			want: []gasPriceStats{
+				{denom: "uosmo", txs: 2, min: 0.001, median: 0.002, max: 0.003},
+				{denom: "uatom", txs: 1, min: 0.01, median: 0.01, max: 0.01},
+				{denom: "ustars", txs: 1, min: 0.005, median: 0.005, max: 0.005},
+			},
+		},
+		{
+			name: "gas wanted without a gas limit",
+			txs:  []tx{{1, []txCoin{{"uatom", "1000"}}, 0, 50000}},
+			want: []gasPriceStats{{denom: "uatom", txs: 1, min: 0.02, median: 0.02, max: 0.02}},
+		},
+		{
+			name: "no gas",
+			txs:  []tx{{1, []txCoin{{"uatom", "1000"}}, 0, 0}},
+		},
+		{
+			name: "prices outside the window are dropped",
+			expired: []gasPriceSeen{
+				{at: time.Now().Add(-feeWindow - time.Minute), denom: "uatom", price: 1},
+				{at: time.Now().Add(-feeWindow - time.Second), denom: "uosmo", price: 1},
+			},
+			txs:  []tx{{1, []txCoin{{"uatom", "1000"}}, 100000, 0}},
+			want: []gasPriceStats{{denom: "uatom", txs: 1, min: 0.01, median: 0.01, max: 0.01}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fees{window: tt.expired}
+			for _, tx := range tt.txs {
+				f.count(feeEvent(tx.height, encodeTx(nil, tx.fee, tx.gasLimit), tx.gasWanted))
+			}
+
+			stats, _ := f.stats()
+			if len(stats) != len(tt.want) {
+				t.Fatalf("stats() = %+v, want %+v", stats, tt.want)
+			}
+			for i := range stats {
+				got, want := stats[i], tt.want[i]
+				if got.denom != want.denom || got.txs != want.txs ||
+					!closeTo(got.min, want.min) || !closeTo(got.median, want.median) || !closeTo(got.max, want.max) {
+					t.Errorf("stats()[%d] = %+v, want %+v", i, got, want)
+				}
+			}
+			for _, seen := range f.window {
+				if time.Since(seen.at) > feeWindow {
+					t.Errorf("window keeps the price %+v", seen)
+				}
+			}
+		})
+	}
+}
+
+func TestFeesBlocks(t *testing.T) {
+	f := &Fees{}
+	f.count(feeEvent(1, encodeTx(nil, []txCoin{{"uatom", "1000"}}, 100000), 0))
+	f.count(feeEvent(2, encodeTx(nil, []txCoin{{"uatom", "1000"}}, 100000), 0))
+	f.count(feeEvent(2, encodeTx(nil, []txCoin{{"uatom", "500"}, {"uosmo", "7"}}, 100000), 0))
+
+	_, blocks := f.stats()
+	want := []blockFees{
+		{height: 2, txs: 2, amounts: map[string]string{"uatom": "1500", "uosmo": "7"}},
+		{height: 1, txs: 1, amounts: map[string]string{"uatom": "1000"}},
+	}
+	if !reflect.DeepEqual(blocks, want) {
+		t.Fatalf("stats() blocks = %+v, want %+v", blocks, want)
+	}
+
+	// the returned amounts are copies, later fees don't change them
+	f.count(feeEvent(2, encodeTx(nil, []txCoin{{"uatom", "500"}}, 100000), 0))
+	if blocks[0].amounts["uatom"] != "1500" {
+		t.Errorf("amounts of a returned block changed to %s", blocks[0].amounts["uatom"])
+	}
+
+	// the oldest blocks are dropped
+	for h := int64(3); h <= feeBlocks+2; h++ {
+		f.count(feeEvent(h, encodeTx(nil, []txCoin{{"uatom", "1"}}, 100000), 0))
+	}
+	_, blocks = f.stats()
+	if len(blocks) != feeBlocks || blocks[0].height != feeBlocks+2 || blocks[len(blocks)-1].height != 3 {
+		t.Errorf("stats() lists %d blocks from %d to %d, want %d from %d to 3",
+			len(blocks), blocks[0].height, blocks[len(blocks)-1].height, feeBlocks, feeBlocks+2)
+	}
+}
+
+// closeTo compares gas prices, which are divided floats.
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// txQuery is the subscription query of all confirmed transactions.
+const txQuery = "tm.event='Tx'"
+
+// txFilterID is the JSON-RPC id of the filtered transaction subscription.
+const txFilterID = 4
+
+// txFilter is the CometBFT query the displayed transactions are filtered by,
+// e.g. message.sender='cosmos1...'.
+type txFilter struct {
+	mu     sync.Mutex
+	filter string
+	// changed wakes the transaction writer up to resubscribe
+	changed chan struct{}
+}
+
+// newTxFilter creates a filter starting with the given query.
+func newTxFilter(filter string) *txFilter {
+	return &txFilter{
+		filter:  filter,
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// set replaces the filter, an empty filter shows all transactions.
+func (f *txFilter) set(filter string) {
+	f.mu.Lock()
+	f.filter = filter
+	f.mu.Unlock()
+
+	select {
+	case f.changed <- struct{}{}:
+	default:
+	}
+}
+
+// get returns the filter.
+func (f *txFilter) get() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.filter
+}
+
+// query returns the subscription query of the filtered transactions or an
+// empty string if there is no filter.
+func (f *txFilter) query() string {
+	filter := f.get()
+	if filter == "" {
+		return ""
+	}
+
+	return txQuery + " AND " + filter
+}
+
+// rpcRequest encodes a JSON-RPC request of a websocket subscription.
+func rpcRequest(method string, query string, id int) string {
+	request, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  map[string]string{"query": query},
+		"id":      id,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return string(request)
+}
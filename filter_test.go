@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestTxFilterQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{"no filter", "", ""},
+		{"sender", "message.sender='cosmos1abc'", "tm.event='Tx' AND message.sender='cosmos1abc'"},
+		{"several conditions", "message.action='send' AND tx.height>5", "tm.event='Tx' AND message.action='send' AND tx.height>5"},
+	}
+
+	for _, tt := range tests {
+		f := newTxFilter(tt.filter)
+		if got := f.get(); got != tt.filter {
+			t.Errorf("%s: get() = %q, want %q", tt.name, got, tt.filter)
+		}
+		if got := f.query(); got != tt.want {
+			t.Errorf("%s: query() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTxFilterSet(t *testing.T) {
+	f := newTxFilter("message.sender='cosmos1abc'")
+
+	// changes made before the writer wakes up are coalesced, set never blocks
+	f.set("")
+	f.set("message.action='send'")
+	select {
+	case <-f.changed:
+	default:
+		t.Fatal("set didn't signal the change")
+	}
+	select {
+	case <-f.changed:
+		t.Fatal("set signaled the change twice")
+	default:
+	}
+
+	if got, want := f.query(), "tm.event='Tx' AND message.action='send'"; got != want {
+		t.Errorf("query() = %q, want %q", got, want)
+	}
+
+	f.set("")
+	if got := f.query(); got != "" {
+		t.Errorf("query() after clearing the filter = %q, want empty", got)
+	}
+}
+
+func TestRPCRequest(t *testing.T) {
+	query := "tm.event='Tx' AND message.memo='say \"hi\"'"
+	request := rpcRequest("subscribe", query, txFilterID)
+
+	if !gjson.Valid(request) {
+		t.Fatalf("rpcRequest() = %s, not valid JSON", request)
+	}
+	for path, want := range map[string]string{
+		"jsonrpc":      "2.0",
+		"method":       "subscribe",
+		"params.query": query,
+		"id":           "4",
+	} {
+		if got := gjson.Get(request, path).String(); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+}
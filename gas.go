@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/barchart"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// gasBlocks is the number of recent blocks the gas table lists.
+const gasBlocks = 20
+
+// gasBuckets are the upper bounds of the gas used per tx histogram, the last
+// bar counts the transactions above them.
+var gasBuckets = []int64{50000, 100000, 200000, 500000, 1000000, 2000000}
+
+// Gas tracks the gas used and wanted by the confirmed transactions
+type Gas struct {
+	mu        sync.Mutex
+	used      int64
+	wanted    int64
+	blocks    []blockGas
+	histogram []int
+}
+
+// blockGas is the gas of the transactions of a block
+type blockGas struct {
+	height int64
+	txs    int
+	used   int64
+	wanted int64
+}
+
+// count counts the gas of a Tx event received over the websocket.
+func (g *Gas) count(message string) {
+	txResult := gjson.Get(message, "result.data.value.TxResult")
+	height := txResult.Get("height").Int()
+	used := txResult.Get("result.gas_used").Int()
+	wanted := txResult.Get("result.gas_wanted").Int()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.histogram == nil {
+		g.histogram = make([]int, len(gasBuckets)+1)
+	}
+
+	g.used += used
+	g.wanted += wanted
+
+	if len(g.blocks) == 0 || g.blocks[len(g.blocks)-1].height != height {
+		g.blocks = append(g.blocks, blockGas{height: height})
+		if len(g.blocks) > gasBlocks {
+			g.blocks = g.blocks[1:]
+		}
+	}
+	b := &g.blocks[len(g.blocks)-1]
+	b.txs++
+	b.used += used
+	b.wanted += wanted
+
+	bucket := 0
+	for bucket < len(gasBuckets) && used > gasBuckets[bucket] {
+		bucket++
+	}
+	g.histogram[bucket]++
+}
+
+// totals returns the gas used and wanted since gex started.
+func (g *Gas) totals() (int64, int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.used, g.wanted
+}
+
+// recent returns the gas of the recent blocks with transactions, the latest
+// first, and the histogram of the gas used per tx.
+func (g *Gas) recent() ([]blockGas, []int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	blocks := make([]blockGas, len(g.blocks))
+	for i, b := range g.blocks {
+		blocks[len(g.blocks)-1-i] = b
+	}
+
+	return blocks, append([]int(nil), g.histogram...)
+}
+
+// gasEfficiency is the share of the wanted gas that was used, in percent.
+func gasEfficiency(used, wanted int64) float64 {
+	// don't divide by 0
+	if wanted <= 0 {
+		return 0
+	}
+
+	return float64(used) / float64(wanted) * 100
+}
+
+// gasPage creates the page comparing the gas used with the gas wanted.
+func gasPage(ctx context.Context, info Info, delay time.Duration) page {
+	gasTableWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := gasTableWidget.Write("Gas will appear as soon as transactions are confirmed in a block.\n"); err != nil {
+		panic(err)
+	}
+
+	histogram, err := barchart.New(
+		barchart.ShowValues(),
+		barchart.BarColors([]cell.Color{cell.ColorNumber(2)}),
+		barchart.ValueColors([]cell.Color{cell.ColorBlack}),
+		barchart.LabelColors([]cell.Color{cell.ColorNumber(2)}),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	go writeGas(ctx, info, gasTableWidget, histogram, delay)
+
+	return page{
+		name: "Gas",
+		layout: []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Gas Used vs. Wanted (blocks with transactions since gex started)"),
+					container.PlaceWidget(gasTableWidget),
+				),
+				container.Bottom(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Gas Used per Tx"),
+					container.PlaceWidget(histogram),
+				),
+				container.SplitPercent(60),
+			),
+		},
+	}
+}
+
+// writeGas writes the gas efficiency of the recent blocks and the histogram
+// of the gas used per tx.
+// Exits when the context expires.
+func writeGas(ctx context.Context, info Info, t *text.Text, bc *barchart.BarChart, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			blocks, histogram := info.gas.recent()
+			if len(blocks) == 0 {
+				continue
+			}
+			used, wanted := info.gas.totals()
+			maxGas := info.blocks.snapshot().maxGasWanted
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "Used %s of %s wanted · Efficiency %.2f%% · Overestimated by %s · Max Gas per Block %s\n\n",
+				numberWithComma(used),
+				numberWithComma(wanted),
+				gasEfficiency(used, wanted),
+				numberWithComma(wanted-used),
+				formatMaxGas(maxGas),
+			)
+			fmt.Fprintf(&b, "%-12s %5s %14s %14s %11s %12s\n", "HEIGHT", "TXS", "USED", "WANTED", "EFFICIENCY", "UTILIZATION")
+			for _, block := range blocks {
+				// a max gas of -1 means the block gas is unlimited
+				utilization := "-"
+				if maxGas > 0 {
+					utilization = fmt.Sprintf("%.2f%%", float64(block.wanted)/float64(maxGas)*100)
+				}
+				fmt.Fprintf(&b, "%-12s %5d %14s %14s %10.2f%% %12s\n",
+					numberWithComma(block.height),
+					block.txs,
+					numberWithComma(block.used),
+					numberWithComma(block.wanted),
+					gasEfficiency(block.used, block.wanted),
+					utilization,
+				)
+			}
+
+			t.Reset()
+			if err := t.Write(b.String()); err != nil {
+				panic(err)
+			}
+
+			max := 0
+			for _, count := range histogram {
+				if count > max {
+					max = count
+				}
+			}
+			if err := bc.Values(histogram, max, barchart.Labels(gasBucketLabels())); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// gasBucketLabels labels the bars of the gas histogram.
+func gasBucketLabels() []string {
+	var labels []string
+	for _, bound := range gasBuckets {
+		labels = append(labels, "≤"+shortGas(bound))
+	}
+
+	return append(labels, ">"+shortGas(gasBuckets[len(gasBuckets)-1]))
+}
+
+// shortGas abbreviates an amount of gas, e.g. 200000 becomes 200k.
+func shortGas(gas int64) string {
+	if gas >= 1000000 && gas%1000000 == 0 {
+		return fmt.Sprintf("%dM", gas/1000000)
+	}
+	if gas >= 1000 && gas%1000 == 0 {
+		return fmt.Sprintf("%dk", gas/1000)
+	}
+
+	return fmt.Sprintf("%d", gas)
+}
+
+// formatMaxGas formats the max gas per block of the consensus params.
+func formatMaxGas(maxGas int64) string {
+	if maxGas <= 0 {
+		return "unlimited"
+	}
+
+	return numberWithComma(maxGas)
+}
@@ -15,5 +15,6 @@ require (
 	github.com/sacOO7/gowebsocket v0.0.0-20210515122958-9396f1a71e23
 	github.com/stretchr/objx v0.3.0 // indirect
 	github.com/tidwall/gjson v1.8.0
+	golang.org/x/net v0.0.0-20200202094626-16171245cfb2
 	gopkg.in/resty.v1 v1.12.0
 )
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// proposalStatuses names the x/gov proposal statuses.
+var proposalStatuses = map[string]string{
+	"PROPOSAL_STATUS_DEPOSIT_PERIOD": "deposit",
+	"PROPOSAL_STATUS_VOTING_PERIOD":  "voting",
+	"PROPOSAL_STATUS_PASSED":         "passed",
+	"PROPOSAL_STATUS_REJECTED":       "rejected",
+	"PROPOSAL_STATUS_FAILED":         "failed",
+}
+
+// proposal is a x/gov proposal of either the v1 or the v1beta1 API.
+type proposal struct {
+	id        string
+	title     string
+	status    string
+	votingEnd time.Time
+	// yes, no, abstain and no with veto
+	tally [4]string
+}
+
+// governancePage creates the page listing the x/gov proposals.
+func governancePage(ctx context.Context, delay time.Duration) page {
+	proposalsWidget, err := text.New(text.WrapAtRunes())
+	if err != nil {
+		panic(err)
+	}
+	if err := proposalsWidget.Write("⌛ loading"); err != nil {
+		panic(err)
+	}
+
+	go writeProposals(ctx, proposalsWidget, delay)
+
+	return page{
+		name: "Governance",
+		layout: []container.Option{
+			container.Border(linestyle.Light),
+			container.BorderTitle("Proposals"),
+			container.PlaceWidget(proposalsWidget),
+		},
+	}
+}
+
+// writeProposals writes the active and recent proposals with their tally.
+// Exits when the context expires.
+func writeProposals(ctx context.Context, t *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			proposals, err := proposalsTable()
+			if err != nil {
+				proposals = fmt.Sprintf("✖️ %s\n", err)
+			}
+
+			t.Reset()
+			if err := t.Write(proposals); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// proposalsTable lists the latest proposals, the ones in their voting period
+// with the current tally and the quorum progress.
+func proposalsTable() (string, error) {
+	version := "v1"
+	proposalsRPC, err := getFromAPI("/cosmos/gov/v1/proposals?pagination.reverse=true&pagination.limit=20")
+	if err != nil {
+		// Cosmos SDK before v0.46
+		version = "v1beta1"
+		proposalsRPC, err = getFromAPI("/cosmos/gov/v1beta1/proposals?pagination.reverse=true&pagination.limit=20")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	tallyParamsRPC, _ := getFromAPI("/cosmos/gov/" + version + "/params/tallying")
+	quorum := gjson.Get(tallyParamsRPC, "tally_params.quorum").String()
+	poolRPC, _ := getFromAPI("/cosmos/staking/v1beta1/pool")
+	bonded := gjson.Get(poolRPC, "pool.bonded_tokens").String()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-5s %-9s %-40s %-16s %8s %8s %8s %8s  %s\n", "ID", "STATUS", "TITLE", "VOTING ENDS", "YES", "NO", "ABSTAIN", "VETO", "TURNOUT / QUORUM")
+	for _, result := range gjson.Get(proposalsRPC, "proposals").Array() {
+		p := parseProposal(result)
+
+		ends := "-"
+		if p.status == "voting" {
+			ends = "in " + formatDuration(time.Until(p.votingEnd))
+			tallyRPC, err := getFromAPI("/cosmos/gov/" + version + "/proposals/" + p.id + "/tally")
+			if err == nil {
+				p.tally = parseTally(gjson.Get(tallyRPC, "tally"))
+			}
+		} else if !p.votingEnd.IsZero() {
+			ends = p.votingEnd.Local().Format("2006-01-02 15:04")
+		}
+
+		total := "0"
+		for _, votes := range p.tally {
+			total = addAmounts(total, votes)
+		}
+		turnout := ""
+		if p.status == "voting" {
+			turnout = fmt.Sprintf("%.2f%% / %.2f%%", ratio(total, bonded)*100, ratio(quorum, "1")*100)
+		}
+
+		fmt.Fprintf(&b, "%-5s %-9s %-40s %-16s %7.2f%% %7.2f%% %7.2f%% %7.2f%%  %s\n",
+			p.id,
+			p.status,
+			shorten(p.title, 40),
+			ends,
+			ratio(p.tally[0], total)*100,
+			ratio(p.tally[1], total)*100,
+			ratio(p.tally[2], total)*100,
+			ratio(p.tally[3], total)*100,
+			turnout,
+		)
+	}
+
+	return b.String(), nil
+}
+
+// parseProposal reads a proposal of the v1 or the v1beta1 API.
+func parseProposal(result gjson.Result) proposal {
+	p := proposal{
+		id:     result.Get("id").String(),
+		title:  result.Get("title").String(),
+		status: proposalStatuses[result.Get("status").String()],
+		tally:  parseTally(result.Get("final_tally_result")),
+	}
+	if p.id == "" {
+		p.id = result.Get("proposal_id").String()
+	}
+	// legacy proposals keep the title in their content
+	for _, path := range []string{"content.title", "messages.0.content.title"} {
+		if p.title == "" {
+			p.title = result.Get(path).String()
+		}
+	}
+	if p.title == "" {
+		p.title = result.Get("messages.0.@type").String()
+	}
+	p.votingEnd, _ = time.Parse(time.RFC3339Nano, result.Get("voting_end_time").String())
+
+	return p
+}
+
+// parseTally reads a tally of the v1 or the v1beta1 API.
+func parseTally(result gjson.Result) [4]string {
+	var tally [4]string
+	for i, option := range []string{"yes", "no", "abstain", "no_with_veto"} {
+		tally[i] = result.Get(option + "_count").String()
+		if tally[i] == "" {
+			tally[i] = result.Get(option).String()
+		}
+	}
+
+	return tally
+}
+
+// formatDuration formats a duration in days, hours and minutes.
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+
+	return fmt.Sprintf("%dm %ds", minutes, (d-minutes*time.Minute)/time.Second)
+}
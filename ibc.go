@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// clientExpiryWarning is how long before its expiry a client is highlighted.
+const clientExpiryWarning = 3 * 24 * time.Hour
+
+// ibcSlowRefresh is how often the queries made per client and per channel are
+// repeated, a hub has hundreds of them.
+const ibcSlowRefresh = 2 * time.Minute
+
+// Packets counts the IBC packets of the confirmed transactions by channel
+type Packets struct {
+	mu     sync.Mutex
+	counts map[string]*packetCounts
+}
+
+// packetCounts are the packets seen on a channel
+type packetCounts struct {
+	sent         int
+	received     int
+	acknowledged int
+}
+
+// packetEvents maps the packet events to the attribute that holds the channel
+// of this chain.
+var packetEvents = map[string]string{
+	"send_packet":        "packet_src_channel",
+	"recv_packet":        "packet_dst_channel",
+	"acknowledge_packet": "packet_src_channel",
+}
+
+// count counts the packet events of a Tx event received over the websocket.
+func (p *Packets) count(message string) {
+	events := gjson.Get(message, "result.events")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.counts == nil {
+		p.counts = map[string]*packetCounts{}
+	}
+
+	for event, attribute := range packetEvents {
+		for _, channel := range events.Get(event + `\.` + attribute).Array() {
+			c, ok := p.counts[channel.String()]
+			if !ok {
+				c = &packetCounts{}
+				p.counts[channel.String()] = c
+			}
+			switch event {
+			case "send_packet":
+				c.sent++
+			case "recv_packet":
+				c.received++
+			case "acknowledge_packet":
+				c.acknowledged++
+			}
+		}
+	}
+}
+
+// get returns the packets seen on the channel.
+func (p *Packets) get(channel string) packetCounts {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if c, ok := p.counts[channel]; ok {
+		return *c
+	}
+
+	return packetCounts{}
+}
+
+// ibcPage creates the page showing the IBC clients, connections and channels.
+func ibcPage(ctx context.Context, info Info, delay time.Duration) page {
+	clientsWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := clientsWidget.Write("⌛ loading"); err != nil {
+		panic(err)
+	}
+
+	channelsWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+
+	go writeIBC(ctx, info, clientsWidget, channelsWidget, delay)
+
+	return page{
+		name: "IBC",
+		layout: []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Clients & Connections"),
+					container.PlaceWidget(clientsWidget),
+				),
+				container.Bottom(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Channels (packets counted since gex started)"),
+					container.PlaceWidget(channelsWidget),
+				),
+				container.SplitPercent(45),
+			),
+		},
+	}
+}
+
+// ibcClient is a light client of a counterparty chain.
+type ibcClient struct {
+	chainID string
+	height  string
+	status  string
+	expiry  time.Time
+}
+
+// ibcCache keeps the results of the queries per client and per channel
+// between the slow refreshes. New clients and channels are queried right away.
+type ibcCache struct {
+	clients   map[string]ibcClient
+	pending   map[string]string
+	refreshed time.Time
+}
+
+// writeIBC writes the clients, connections and channels of IBC.
+// Exits when the context expires.
+func writeIBC(ctx context.Context, info Info, tClients *text.Text, tChannels *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	cache := &ibcCache{clients: map[string]ibcClient{}, pending: map[string]string{}}
+	for {
+		select {
+		case <-ticker.C:
+			slow := time.Since(cache.refreshed) > ibcSlowRefresh
+			if slow {
+				cache.refreshed = time.Now()
+			}
+
+			clients, err := queryClients(cache, slow)
+			if err != nil {
+				tClients.Reset()
+				if err := tClients.Write(err.Error()); err != nil {
+					panic(err)
+				}
+				continue
+			}
+			connectionsRPC, err := getFromAPI("/ibc/core/connection/v1/connections?pagination.limit=1000")
+			if err != nil {
+				continue
+			}
+			channelsRPC, err := getFromAPI("/ibc/core/channel/v1/channels?pagination.limit=1000")
+			if err != nil {
+				continue
+			}
+
+			tClients.Reset()
+			writeClients(tClients, clients)
+			connectionClients := writeConnections(tClients, connectionsRPC)
+			tChannels.Reset()
+			writeChannels(tChannels, info, channelsRPC, connectionClients, clients, cache, slow)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// queryClients queries the IBC light clients by client id. The status and the
+// expiry of the known clients are taken from the cache unless slow is set, the
+// consensus state is queried again only once the client was updated.
+func queryClients(cache *ibcCache, slow bool) (map[string]ibcClient, error) {
+	clientStatesRPC, err := getFromAPI("/ibc/core/client/v1/client_states?pagination.limit=1000")
+	if err != nil {
+		return nil, err
+	}
+
+	clients := map[string]ibcClient{}
+	// the cached expiries stay with the height of their consensus state
+	cached := map[string]ibcClient{}
+	for _, cs := range gjson.Get(clientStatesRPC, "client_states").Array() {
+		id := cs.Get("client_id").String()
+		state := cs.Get("client_state")
+		revision := state.Get("latest_height.revision_number").String()
+		height := state.Get("latest_height.revision_height").String()
+
+		client := ibcClient{
+			chainID: state.Get("chain_id").String(),
+			height:  revision + "-" + height,
+		}
+		last, ok := cache.clients[id]
+		if ok && !slow {
+			client.status, client.expiry = last.status, last.expiry
+			clients[id] = client
+			cached[id] = last
+			continue
+		}
+		if statusRPC, err := getFromAPI("/ibc/core/client/v1/client_status/" + id); err == nil {
+			client.status = gjson.Get(statusRPC, "status").String()
+		}
+
+		// the client expires once the trusting period passed since its latest consensus state
+		trustingPeriod, err := time.ParseDuration(state.Get("trusting_period").String())
+		if ok && last.height == client.height {
+			client.expiry = last.expiry
+		} else if err == nil {
+			consensusRPC, err := getFromAPI("/ibc/core/client/v1/consensus_states/" + id + "/revision/" + revision + "/height/" + height)
+			if err == nil {
+				timestamp, err := time.Parse(time.RFC3339Nano, gjson.Get(consensusRPC, "consensus_state.timestamp").String())
+				if err == nil {
+					client.expiry = timestamp.Add(trustingPeriod)
+				}
+			}
+		}
+		clients[id] = client
+		cached[id] = client
+	}
+	cache.clients = cached
+
+	return clients, nil
+}
+
+// writeClients writes the clients, the ones close to their expiry in red.
+func writeClients(t *text.Text, clients map[string]ibcClient) {
+	var ids []string
+	for id := range clients {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	if err := t.Write(fmt.Sprintf("%-22s %-24s %-16s %-8s %s\n", "CLIENT", "CHAIN", "HEIGHT", "STATUS", "EXPIRES")); err != nil {
+		panic(err)
+	}
+	for _, id := range ids {
+		c := clients[id]
+		expires, color := "-", cell.ColorDefault
+		if !c.expiry.IsZero() {
+			expires = "in " + formatDuration(time.Until(c.expiry))
+			if time.Until(c.expiry) < clientExpiryWarning {
+				color = cell.ColorRed
+			}
+		}
+		if c.status != "" && c.status != "Active" {
+			color = cell.ColorRed
+		}
+
+		line := fmt.Sprintf("%-22s %-24s %-16s %-8s %s\n", id, shorten(c.chainID, 24), c.height, c.status, expires)
+		if err := t.Write(line, text.WriteCellOpts(cell.FgColor(color))); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// writeConnections writes the connections and returns their client ids.
+func writeConnections(t *text.Text, connectionsRPC string) map[string]string {
+	connectionClients := map[string]string{}
+
+	if err := t.Write(fmt.Sprintf("\n%-22s %-22s %-10s %s\n", "CONNECTION", "CLIENT", "STATE", "COUNTERPARTY")); err != nil {
+		panic(err)
+	}
+	for _, c := range gjson.Get(connectionsRPC, "connections").Array() {
+		connectionClients[c.Get("id").String()] = c.Get("client_id").String()
+
+		state := strings.TrimPrefix(c.Get("state").String(), "STATE_")
+		line := fmt.Sprintf("%-22s %-22s %-10s %s/%s\n",
+			c.Get("id").String(),
+			c.Get("client_id").String(),
+			state,
+			c.Get("counterparty.connection_id").String(),
+			c.Get("counterparty.client_id").String(),
+		)
+		if err := t.Write(line, text.WriteCellOpts(cell.FgColor(stateColor(state)))); err != nil {
+			panic(err)
+		}
+	}
+
+	return connectionClients
+}
+
+// writeChannels writes the channels with their counterparty and packets.
+// The pending packets are taken from the cache unless slow is set.
+func writeChannels(t *text.Text, info Info, channelsRPC string, connectionClients map[string]string, clients map[string]ibcClient, cache *ibcCache, slow bool) {
+	if err := t.Write(fmt.Sprintf("%-14s %-16s %-10s %-10s %-22s %-30s %8s %8s %8s %8s\n",
+		"CHANNEL", "PORT", "STATE", "ORDER", "COUNTERPARTY CHAIN", "COUNTERPARTY", "SENT", "RECV", "ACKED", "PENDING")); err != nil {
+		panic(err)
+	}
+	pendingByChannel := map[string]string{}
+	for _, c := range gjson.Get(channelsRPC, "channels").Array() {
+		channel := c.Get("channel_id").String()
+		port := c.Get("port_id").String()
+		client := connectionClients[c.Get("connection_hops.0").String()]
+
+		// packets committed on this chain that are still waiting for their acknowledgement
+		pending, ok := cache.pending[port+"/"+channel]
+		if !ok || slow {
+			pending = "-"
+			commitmentsRPC, err := getFromAPI("/ibc/core/channel/v1/channels/" + channel + "/ports/" + port + "/packet_commitments?pagination.count_total=true&pagination.limit=1")
+			if err == nil {
+				pending = gjson.Get(commitmentsRPC, "pagination.total").String()
+			}
+		}
+		pendingByChannel[port+"/"+channel] = pending
+
+		packets := info.packets.get(channel)
+		state := strings.TrimPrefix(c.Get("state").String(), "STATE_")
+		line := fmt.Sprintf("%-14s %-16s %-10s %-10s %-22s %-30s %8d %8d %8d %8s\n",
+			channel,
+			shorten(port, 16),
+			state,
+			strings.TrimPrefix(c.Get("ordering").String(), "ORDER_"),
+			shorten(clients[client].chainID, 22),
+			shorten(c.Get("counterparty.port_id").String()+"/"+c.Get("counterparty.channel_id").String(), 30),
+			packets.sent,
+			packets.received,
+			packets.acknowledged,
+			pending,
+		)
+		if err := t.Write(line, text.WriteCellOpts(cell.FgColor(stateColor(state)))); err != nil {
+			panic(err)
+		}
+	}
+	cache.pending = pendingByChannel
+}
+
+// stateColor highlights connections and channels that aren't open.
+func stateColor(state string) cell.Color {
+	switch state {
+	case "OPEN":
+		return cell.ColorDefault
+	case "CLOSED", "UNINITIALIZED":
+		return cell.ColorRed
+	}
+
+	return cell.ColorYellow
+}
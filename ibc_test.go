@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// restAPI serves the responses by path prefix and counts the requests.
+func restAPI(t *testing.T, responses map[string]string) map[string]int {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for prefix, response := range responses {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				mu.Lock()
+				requests[prefix]++
+				mu.Unlock()
+				w.Write([]byte(response))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(api.Close)
+
+	saved := *givenAPI
+	*givenAPI = api.URL
+	t.Cleanup(func() { *givenAPI = saved })
+
+	return requests
+}
+
+func TestQueryClientsCache(t *testing.T) {
+	responses := map[string]string{
+		"/ibc/core/client/v1/client_states":     `{"client_states":[{"client_id":"07-tendermint-0","client_state":{"chain_id":"osmosis-1","trusting_period":"864000s","latest_height":{"revision_number":"1","revision_height":"100"}}}]}`,
+		"/ibc/core/client/v1/client_status/":    `{"status":"Active"}`,
+		"/ibc/core/client/v1/consensus_states/": `{"consensus_state":{"timestamp":"2026-01-01T00:00:00Z"}}`,
+	}
+	requests := restAPI(t, responses)
+	cache := &ibcCache{clients: map[string]ibcClient{}, pending: map[string]string{}}
+
+	tests := []struct {
+		name       string
+		height     string
+		slow       bool
+		statuses   int
+		consensus  int
+		wantHeight string
+	}{
+		{"new client", "100", false, 1, 1, "1-100"},
+		{"known client", "100", false, 1, 1, "1-100"},
+		{"updated client between the refreshes", "200", false, 1, 1, "1-200"},
+		{"slow refresh of the updated client", "200", true, 2, 2, "1-200"},
+		{"slow refresh of an unchanged client", "200", true, 3, 2, "1-200"},
+	}
+
+	for _, tt := range tests {
+		responses["/ibc/core/client/v1/client_states"] = strings.Replace(responses["/ibc/core/client/v1/client_states"], `"revision_height":"100"`, `"revision_height":"`+tt.height+`"`, 1)
+		clients, err := queryClients(cache, tt.slow)
+		if err != nil {
+			t.Fatalf("%s: queryClients error = %v", tt.name, err)
+		}
+		client := clients["07-tendermint-0"]
+		if client.height != tt.wantHeight || client.status != "Active" || client.expiry.IsZero() {
+			t.Errorf("%s: client = %+v", tt.name, client)
+		}
+		if requests["/ibc/core/client/v1/client_status/"] != tt.statuses || requests["/ibc/core/client/v1/consensus_states/"] != tt.consensus {
+			t.Errorf("%s: %d status and %d consensus state queries, want %d and %d", tt.name,
+				requests["/ibc/core/client/v1/client_status/"], requests["/ibc/core/client/v1/consensus_states/"], tt.statuses, tt.consensus)
+		}
+	}
+}
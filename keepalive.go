@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/sacOO7/gowebsocket"
+	"github.com/tidwall/gjson"
+)
+
+// pingInterval is how often the websocket is pinged and its subscriptions are
+// checked.
+const pingInterval = 10 * time.Second
+
+// pongTimeout is how long a pong may take before the websocket is considered
+// dead, e.g. a half-open connection dropped by a load balancer.
+const pongTimeout = 5 * time.Second
+
+// staleBlocks is how many blocks the RPC may be ahead of the last heartbeat
+// before the subscriptions are considered stale.
+const staleBlocks = 3
+
+// heartbeatQuery is subscribed on every websocket to notice subscriptions
+// that stop delivering events while the chain advances.
+const heartbeatQuery = "tm.event='NewBlockHeader'"
+
+// heartbeatID is the JSON-RPC id of the heartbeat subscription.
+const heartbeatID = 99
+
+// resubscribeID is the JSON-RPC id of the request dropping stale subscriptions.
+const resubscribeID = 98
+
+// maxReconnects is how many reconnects may fail in a row before the events
+// are polled until the websocket is back.
+const maxReconnects = 3
+
+// websocketSource receives the events over the websocket. It pings the node,
+// renews stale subscriptions and reconnects when the websocket is dead, and
+// polls the RPC while reconnecting keeps failing.
+type websocketSource struct {
+	mu      sync.Mutex
+	socket  *gowebsocket.Socket
+	onEvent func(message string)
+	subs    []subscribedQuery
+	// generation ignores the messages of replaced sockets
+	generation int
+	lastPong   time.Time
+	// heartbeat is false if the node doesn't support the heartbeat query
+	heartbeat       bool
+	heartbeatHeight int64
+	resubscribed    bool
+	// failures counts the failed reconnects in a row
+	failures int
+	// fallback polls the subscriptions while the websocket is down
+	fallback *pollingSource
+	done     chan struct{}
+	closed   bool
+}
+
+// subscribedQuery is a subscription to replay after a reconnect.
+type subscribedQuery struct {
+	query string
+	id    int
+}
+
+// connect opens a websocket to the active endpoint and subscribes the
+// heartbeat and the subscriptions.
+func (s *websocketSource) connect() bool {
+	s.mu.Lock()
+	s.generation++
+	generation := s.generation
+	s.mu.Unlock()
+
+	socket := newSocket(endpoints.current())
+	socket.OnTextMessage = func(message string, _ gowebsocket.Socket) {
+		s.receive(generation, message)
+	}
+	socket.OnPongReceived = func(_ string, _ gowebsocket.Socket) {
+		s.mu.Lock()
+		s.lastPong = time.Now()
+		s.mu.Unlock()
+	}
+	socket.Connect()
+	// gowebsocket only sets IsConnected with an OnConnected callback
+	if socket.Conn == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	// closed while dialing
+	if s.closed {
+		s.mu.Unlock()
+		socket.Close()
+		return false
+	}
+	s.socket = &socket
+	s.lastPong = time.Now()
+	s.heartbeat = true
+	s.heartbeatHeight = 0
+	s.resubscribed = false
+	subs := append([]subscribedQuery(nil), s.subs...)
+	s.mu.Unlock()
+
+	s.send(rpcRequest("subscribe", heartbeatQuery, heartbeatID))
+	for _, sub := range subs {
+		s.send(rpcRequest("subscribe", sub.query, sub.id))
+	}
+
+	return true
+}
+
+// receive tracks the heartbeat, renews the subscriptions the node dropped and
+// passes the events on.
+func (s *websocketSource) receive(generation int, message string) {
+	s.mu.Lock()
+	if generation != s.generation {
+		s.mu.Unlock()
+		return
+	}
+	// the node cancels a subscription with an error on its event id, e.g.
+	// when the client is not pulling messages fast enough
+	if id, ok := eventID(gjson.Get(message, "id").String()); ok && gjson.Get(message, "error").Exists() && s.subscribed(id) {
+		s.mu.Unlock()
+		s.resubscribe()
+		return
+	}
+	if gjson.Get(message, "id").Int() == heartbeatID && gjson.Get(message, "error").Exists() {
+		s.heartbeat = false
+		s.mu.Unlock()
+		return
+	}
+	if gjson.Get(message, "result.query").String() == heartbeatQuery {
+		height := gjson.Get(message, "result.data.value.header.height").Int()
+		if height > s.heartbeatHeight {
+			s.heartbeatHeight = height
+			s.resubscribed = false
+		}
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	s.onEvent(message)
+}
+
+// subscribed returns whether the id is of the heartbeat or a subscription.
+// The lock must be held.
+func (s *websocketSource) subscribed(id int) bool {
+	if id == heartbeatID {
+		return true
+	}
+	for _, sub := range s.subs {
+		if sub.id == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eventID reads the id of the subscription an event or a cancellation is
+// sent for, e.g. 1#event.
+func eventID(id string) (int, bool) {
+	if !strings.HasSuffix(id, "#event") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(id, "#event"))
+
+	return n, err == nil
+}
+
+// send sends a message over the current socket.
+func (s *websocketSource) send(message string) {
+	s.mu.Lock()
+	socket := s.socket
+	s.mu.Unlock()
+
+	socket.SendText(message)
+}
+
+func (s *websocketSource) subscribe(query string, id int) {
+	s.mu.Lock()
+	s.subs = append(s.subs, subscribedQuery{query: query, id: id})
+	if s.fallback != nil {
+		s.fallback.subscribe(query, id)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	s.send(rpcRequest("subscribe", query, id))
+}
+
+func (s *websocketSource) unsubscribe(query string, id int) {
+	s.mu.Lock()
+	subs := s.subs[:0]
+	for _, sub := range s.subs {
+		if sub.query != query || sub.id != id {
+			subs = append(subs, sub)
+		}
+	}
+	s.subs = subs
+	if s.fallback != nil {
+		s.fallback.unsubscribe(query, id)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	s.send(rpcRequest("unsubscribe", query, id))
+}
+
+func (s *websocketSource) close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	socket := s.socket
+	if s.fallback != nil {
+		s.fallback.close()
+		s.fallback = nil
+	}
+	s.mu.Unlock()
+	// after closed is set, so a reconnect can't record the mode again
+	forgetEventMode(s)
+
+	socket.Close()
+}
+
+// keepalive pings the websocket and checks the subscriptions against the
+// height of the RPC. Exits when the source is closed.
+func (s *websocketSource) keepalive() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			socket := s.socket
+			pongOverdue := time.Since(s.lastPong) > pingInterval+pongTimeout
+			s.mu.Unlock()
+
+			if pongOverdue {
+				s.reconnect()
+				continue
+			}
+			// control frames may be written concurrently with the messages
+			if err := socket.Conn.WriteControl(gorilla.PingMessage, nil, time.Now().Add(pongTimeout)); err != nil {
+				s.reconnect()
+				continue
+			}
+
+			switch s.staleness() {
+			case "resubscribe":
+				s.resubscribe()
+			case "reconnect":
+				s.reconnect()
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// staleness compares the heartbeat with the height of the RPC. Stale
+// subscriptions are renewed first and the websocket is reconnected if they
+// stay stale.
+func (s *websocketSource) staleness() string {
+	s.mu.Lock()
+	heartbeat, heartbeatHeight, resubscribed := s.heartbeat, s.heartbeatHeight, s.resubscribed
+	s.mu.Unlock()
+
+	if !heartbeat {
+		return ""
+	}
+	statusRPC, err := getFromRPC("status")
+	if err != nil {
+		return ""
+	}
+	latest := gjson.Get(statusRPC, "result.sync_info.latest_block_height").Int()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the first heartbeat is still to come
+	if s.heartbeatHeight == 0 {
+		s.heartbeatHeight = latest
+		return ""
+	}
+	if latest-heartbeatHeight < staleBlocks {
+		return ""
+	}
+	if resubscribed {
+		return "reconnect"
+	}
+
+	return "resubscribe"
+}
+
+// resubscribe renews all subscriptions on the websocket.
+func (s *websocketSource) resubscribe() {
+	s.mu.Lock()
+	s.resubscribed = true
+	subs := append([]subscribedQuery(nil), s.subs...)
+	s.mu.Unlock()
+
+	s.send(fmt.Sprintf(`{"jsonrpc":"2.0","method":"unsubscribe_all","params":{},"id":%d}`, resubscribeID))
+	s.send(rpcRequest("subscribe", heartbeatQuery, heartbeatID))
+	for _, sub := range subs {
+		s.send(rpcRequest("subscribe", sub.query, sub.id))
+	}
+}
+
+// reconnect replaces the websocket, the subscriptions are replayed. A failed
+// reconnect is retried on the next ping, after maxReconnects the
+// subscriptions are polled until a reconnect succeeds.
+func (s *websocketSource) reconnect() {
+	s.mu.Lock()
+	closed := s.closed
+	socket := s.socket
+	s.mu.Unlock()
+	if closed {
+		return
+	}
+
+	socket.Close()
+	connected := s.connect()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	if connected {
+		s.failures = 0
+		if s.fallback != nil {
+			s.fallback.close()
+			s.fallback = nil
+			setEventMode(s, false)
+		}
+		return
+	}
+
+	s.failures++
+	if s.failures < maxReconnects || s.fallback != nil {
+		return
+	}
+	s.fallback = &pollingSource{onEvent: s.onEvent}
+	for _, sub := range s.subs {
+		s.fallback.subscribe(sub.query, sub.id)
+	}
+	setEventMode(s, true)
+}
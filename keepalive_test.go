@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/tidwall/gjson"
+)
+
+func TestEventID(t *testing.T) {
+	tests := []struct {
+		id    string
+		want  int
+		valid bool
+	}{
+		{"1#event", 1, true},
+		{"99#event", 99, true},
+		{"1", 0, false},
+		{"", 0, false},
+		{"x#event", 0, false},
+	}
+
+	for _, tt := range tests {
+		id, ok := eventID(tt.id)
+		if id != tt.want || ok != tt.valid {
+			t.Errorf("eventID(%q) = %d, %v, want %d, %v", tt.id, id, ok, tt.want, tt.valid)
+		}
+	}
+}
+
+// websocketNode serves a websocket that passes the methods of the requests to
+// methods and the connections to conns.
+func websocketNode(t *testing.T, methods chan<- string, conns chan<- *gorilla.Conn) *httptest.Server {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&gorilla.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- conn
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			methods <- gjson.GetBytes(message, "method").String()
+		}
+	}))
+	t.Cleanup(node.Close)
+
+	saved := endpoints.list
+	endpoints.list = []rpcEndpoint{{url: node.URL, ws: "ws" + strings.TrimPrefix(node.URL, "http") + "/websocket"}}
+	t.Cleanup(func() { endpoints.list = saved })
+
+	return node
+}
+
+// expectMethods fails unless the node receives the methods in order.
+func expectMethods(t *testing.T, methods <-chan string, want ...string) {
+	t.Helper()
+	for _, method := range want {
+		select {
+		case got := <-methods:
+			if got != method {
+				t.Fatalf("node received %s, want %s", got, method)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("node didn't receive %s", method)
+		}
+	}
+}
+
+func TestWebsocketResubscribe(t *testing.T) {
+	methods, conns := make(chan string, 10), make(chan *gorilla.Conn, 1)
+	websocketNode(t, methods, conns)
+
+	source := &websocketSource{onEvent: func(string) {}, done: make(chan struct{})}
+	if !source.connect() {
+		t.Fatal("connect() failed")
+	}
+	defer source.close()
+	source.subscribe(blockQuery, 1)
+	expectMethods(t, methods, "subscribe", "subscribe")
+
+	// the node drops a subscription of a slow client
+	conn := <-conns
+	cancelled := `{"jsonrpc":"2.0","id":"1#event","error":{"code":-32000,"message":"Server error","data":"subscription was canceled (reason: client is not pulling messages fast enough)"}}`
+	if err := conn.WriteMessage(gorilla.TextMessage, []byte(cancelled)); err != nil {
+		t.Fatal(err)
+	}
+	expectMethods(t, methods, "unsubscribe_all", "subscribe", "subscribe")
+}
+
+func TestWebsocketFallback(t *testing.T) {
+	methods, conns := make(chan string, 10), make(chan *gorilla.Conn, 1)
+	node := websocketNode(t, methods, conns)
+
+	source := &websocketSource{onEvent: func(string) {}, done: make(chan struct{})}
+	if !source.connect() {
+		t.Fatal("connect() failed")
+	}
+	setEventMode(source, false)
+	source.subscribe(blockQuery, 1)
+
+	// the node goes away
+	node.Close()
+	(<-conns).Close()
+	for i := 0; i < maxReconnects; i++ {
+		if sourcePolling(source) {
+			t.Fatalf("polling after %d failed reconnects, want %d", i, maxReconnects)
+		}
+		source.reconnect()
+	}
+	if !sourcePolling(source) {
+		t.Fatalf("not polling after %d failed reconnects", maxReconnects)
+	}
+
+	source.close()
+	poller.mu.Lock()
+	subs := len(poller.subs)
+	poller.mu.Unlock()
+	if subs != 0 || sourcePolling(source) {
+		t.Errorf("closed source left %d polled subscriptions, polling %v", subs, sourcePolling(source))
+	}
+}
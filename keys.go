@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// keyBinding binds one or more keys to an action.
+type keyBinding struct {
+	keys []keyboard.Key
+	// hint is shown in the status bar, bindings without a hint are hidden
+	hint   string
+	action func()
+}
+
+// keyBindings is the set of global key bindings of the dashboard.
+type keyBindings []keyBinding
+
+// subscriber returns the keyboard subscriber that dispatches key presses to
+// the bound actions. The captures, e.g. an open prompt, get to see the key
+// first and stop the dispatch by reporting the key as consumed.
+func (kb keyBindings) subscriber(captures ...func(k *terminalapi.Keyboard) bool) func(k *terminalapi.Keyboard) {
+	return func(k *terminalapi.Keyboard) {
+		for _, capture := range captures {
+			if capture(k) {
+				return
+			}
+		}
+		for _, b := range kb {
+			for _, key := range b.keys {
+				if k.Key == key {
+					b.action()
+					return
+				}
+			}
+		}
+	}
+}
+
+// hints returns the hints of all bindings joined for the status bar.
+func (kb keyBindings) hints() string {
+	var hints []string
+	for _, b := range kb {
+		if b.hint != "" {
+			hints = append(hints, b.hint)
+		}
+	}
+
+	return strings.Join(hints, " · ")
+}
+
+// prompt is a single line input shown in the status bar. While it is open it
+// captures all key presses.
+type prompt struct {
+	status *text.Text
+	label  string
+	input  []rune
+	active bool
+	submit func(input string)
+	// closed is called when the prompt closes so the status bar can be restored
+	closed func()
+}
+
+// newPrompt creates a prompt that renders into the status bar.
+func newPrompt(status *text.Text, closed func()) *prompt {
+	return &prompt{
+		status: status,
+		closed: closed,
+	}
+}
+
+// open shows the prompt with the label and the initial input, submit is
+// called with the trimmed input once Enter is pressed, even if it's empty.
+func (p *prompt) open(label string, input string, submit func(input string)) {
+	p.label = label
+	p.input = []rune(input)
+	p.submit = submit
+	p.active = true
+	p.render()
+}
+
+// close hides the prompt.
+func (p *prompt) close() {
+	p.active = false
+	p.closed()
+}
+
+// capture handles the key press while the prompt is open and reports whether
+// the key was consumed.
+func (p *prompt) capture(k *terminalapi.Keyboard) bool {
+	if !p.active {
+		return false
+	}
+
+	switch k.Key {
+	case keyboard.KeyEsc:
+		p.close()
+		return true
+	case keyboard.KeyEnter:
+		p.close()
+		p.submit(strings.TrimSpace(string(p.input)))
+		return true
+	case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	default:
+		// printable characters only, special keys are negative or control codes
+		if k.Key >= keyboard.KeySpace {
+			p.input = append(p.input, rune(k.Key))
+		}
+	}
+	p.render()
+
+	return true
+}
+
+// render writes the prompt to the status bar.
+func (p *prompt) render() {
+	p.status.Reset()
+	if err := p.status.Write(" "+p.label+": ", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(2)))); err != nil {
+		panic(err)
+	}
+	if err := p.status.Write(string(p.input) + "█"); err != nil {
+		panic(err)
+	}
+	if err := p.status.Write("   enter submit · esc cancel", text.WriteCellOpts(cell.FgColor(cell.ColorNumber(8)))); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/sparkline"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// latencySamples is the number of recent samples the percentiles cover.
+const latencySamples = 200
+
+// eventSeries is the name of the delay between the header time of a block and
+// the arrival of its NewBlock event.
+const eventSeries = "NewBlock event"
+
+// Latencies tracks the round trips of the RPC methods and the delay of the
+// NewBlock events.
+type Latencies struct {
+	mu     sync.Mutex
+	series map[string]*latencySeries
+}
+
+// latencySeries are the recent samples of an RPC method or of the events,
+// failed calls are counted without a sample.
+type latencySeries struct {
+	samples []time.Duration
+	calls   int
+	errors  int
+}
+
+// latencyStats are the percentiles of a series.
+type latencyStats struct {
+	name   string
+	calls  int
+	errors int
+	last   time.Duration
+	p50    time.Duration
+	p90    time.Duration
+	p99    time.Duration
+	max    time.Duration
+}
+
+// latencies are measured by getFromRPC and the block writer.
+var latencies Latencies
+
+// observe adds a sample to a series.
+func (l *Latencies) observe(name string, d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.get(name)
+	s.calls++
+	s.samples = append(s.samples, d)
+	if len(s.samples) > latencySamples {
+		s.samples = s.samples[1:]
+	}
+}
+
+// fail counts a failed call of a series.
+func (l *Latencies) fail(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.get(name)
+	s.calls++
+	s.errors++
+}
+
+// get returns a series, created on first use. The lock must be held.
+func (l *Latencies) get(name string) *latencySeries {
+	if l.series == nil {
+		l.series = map[string]*latencySeries{}
+	}
+	s, ok := l.series[name]
+	if !ok {
+		s = &latencySeries{}
+		l.series[name] = s
+	}
+
+	return s
+}
+
+// samples returns the recent samples of a series, the oldest first.
+func (l *Latencies) samples(name string) []time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s, ok := l.series[name]
+	if !ok {
+		return nil
+	}
+
+	return append([]time.Duration(nil), s.samples...)
+}
+
+// stats returns the percentiles of the RPC methods, sorted by name, and of the
+// events.
+func (l *Latencies) stats() ([]latencyStats, latencyStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var methods []latencyStats
+	events := latencyStats{name: eventSeries}
+	for name, s := range l.series {
+		stats := latencyStats{name: name, calls: s.calls, errors: s.errors}
+		// a method that only failed has no samples
+		if len(s.samples) > 0 {
+			sorted := append([]time.Duration(nil), s.samples...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			stats.last = s.samples[len(s.samples)-1]
+			stats.p50 = percentile(sorted, 50)
+			stats.p90 = percentile(sorted, 90)
+			stats.p99 = percentile(sorted, 99)
+			stats.max = sorted[len(sorted)-1]
+		}
+		if name == eventSeries {
+			events = stats
+			continue
+		}
+		methods = append(methods, stats)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].name < methods[j].name })
+
+	return methods, events
+}
+
+// percentile returns the nearest rank percentile of sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted)+99)/100 - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
+
+// rpcMethod returns the RPC method of an endpoint without its parameters.
+func rpcMethod(endpoint string) string {
+	if i := strings.Index(endpoint, "?"); i >= 0 {
+		return endpoint[:i]
+	}
+
+	return endpoint
+}
+
+// latencyPage creates the page with the latency of the RPC and the events.
+func latencyPage(ctx context.Context, delay time.Duration) page {
+	latencyTableWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+
+	rpcSparkline, err := sparkline.New(sparkline.Color(cell.ColorNumber(2)))
+	if err != nil {
+		panic(err)
+	}
+	eventSparkline, err := sparkline.New(sparkline.Color(cell.ColorNumber(33)))
+	if err != nil {
+		panic(err)
+	}
+
+	go writeLatency(ctx, latencyTableWidget, rpcSparkline, eventSparkline, delay)
+
+	return page{
+		name: "Latency",
+		layout: []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.Border(linestyle.Light),
+					container.BorderTitle(fmt.Sprintf("Round Trips (last %d samples)", latencySamples)),
+					container.PlaceWidget(latencyTableWidget),
+				),
+				container.Bottom(
+					container.SplitHorizontal(
+						container.Top(
+							container.Border(linestyle.Light),
+							container.BorderTitle("health round trip (ms)"),
+							container.PlaceWidget(rpcSparkline),
+						),
+						container.Bottom(
+							container.Border(linestyle.Light),
+							container.BorderTitle("Block header time to NewBlock event (ms)"),
+							container.PlaceWidget(eventSparkline),
+						),
+					),
+				),
+				container.SplitPercent(50),
+			),
+		},
+	}
+}
+
+// writeLatency writes the percentiles of the RPC methods and the events and
+// the sparklines of the health round trip and the event delay.
+// Exits when the context expires.
+func writeLatency(ctx context.Context, t *text.Text, rpc *sparkline.SparkLine, events *sparkline.SparkLine, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			methods, eventStats := latencies.stats()
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "%-20s %8s %8s %10s %10s %10s %10s %10s\n", "RPC METHOD", "CALLS", "ERRORS", "LAST", "P50", "P90", "P99", "MAX")
+			for _, s := range append(methods, eventStats) {
+				if s.calls == s.errors {
+					fmt.Fprintf(&b, "%-20s %8d %8d %10s\n", shorten(s.name, 20), s.calls, s.errors, "-")
+					continue
+				}
+				fmt.Fprintf(&b, "%-20s %8d %8d %10s %10s %10s %10s %10s\n",
+					shorten(s.name, 20),
+					s.calls,
+					s.errors,
+					formatLatency(s.last),
+					formatLatency(s.p50),
+					formatLatency(s.p90),
+					formatLatency(s.p99),
+					formatLatency(s.max),
+				)
+			}
+			b.WriteString("\nThe event delay includes the time the validators need to commit the block, polled blocks aren't measured.\n")
+
+			t.Reset()
+			if err := t.Write(b.String()); err != nil {
+				panic(err)
+			}
+
+			rpc.Clear()
+			if err := rpc.Add(milliseconds(latencies.samples("health"))); err != nil {
+				panic(err)
+			}
+			events.Clear()
+			if err := events.Add(milliseconds(latencies.samples(eventSeries))); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// milliseconds converts samples for a sparkline.
+func milliseconds(samples []time.Duration) []int {
+	ms := make([]int, len(samples))
+	for i, d := range samples {
+		ms[i] = int(d.Milliseconds())
+	}
+
+	return ms
+}
+
+// formatLatency formats a latency with millisecond precision.
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%d ms", d.Milliseconds())
+	}
+	if d < time.Minute {
+		return fmt.Sprintf("%.2f s", d.Seconds())
+	}
+
+	return d.Round(time.Second).String()
+}
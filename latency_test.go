@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	samples := func(n int) []time.Duration {
+		sorted := make([]time.Duration, n)
+		for i := range sorted {
+			sorted[i] = time.Duration(i+1) * time.Millisecond
+		}
+		return sorted
+	}
+
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      int
+		want   time.Duration
+	}{
+		{"single sample", samples(1), 50, time.Millisecond},
+		{"p0", samples(10), 0, time.Millisecond},
+		{"p50 of even", samples(10), 50, 5 * time.Millisecond},
+		{"p50 of odd", samples(5), 50, 3 * time.Millisecond},
+		{"p90", samples(10), 90, 9 * time.Millisecond},
+		{"p99 of few", samples(10), 99, 10 * time.Millisecond},
+		{"p99 of 200", samples(200), 99, 198 * time.Millisecond},
+		{"p100", samples(200), 100, 200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%d samples, %d) = %s, want %s", len(tt.sorted), tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatenciesStats(t *testing.T) {
+	var l Latencies
+	l.observe("status", 30*time.Millisecond)
+	l.observe("status", 10*time.Millisecond)
+	l.fail("status")
+	l.fail("validators")
+	l.observe(eventSeries, -time.Second)
+
+	methods, events := l.stats()
+	if len(methods) != 2 {
+		t.Fatalf("stats() = %d methods, want 2", len(methods))
+	}
+
+	status := methods[0]
+	if status.name != "status" || status.calls != 3 || status.errors != 1 || status.last != 10*time.Millisecond || status.max != 30*time.Millisecond {
+		t.Errorf("stats of status = %+v", status)
+	}
+	validators := methods[1]
+	if validators.name != "validators" || validators.calls != 1 || validators.errors != 1 || validators.max != 0 {
+		t.Errorf("stats of validators = %+v", validators)
+	}
+	// a clock ahead of the block time doesn't give negative delays
+	if events.calls != 1 || events.last != 0 {
+		t.Errorf("stats of the events = %+v", events)
+	}
+}
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// writeVerification writes the result of a verification below the height in
+// the Latest Block widget.
+func writeVerification(t textWidget, lc *lightClient, err error) {
+	if err != nil {
+		if err := t.Write("\n✖ "+err.Error(), text.WriteCellOpts(cell.FgColor(cell.ColorRed))); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if err := t.Write("\n✔ verified", text.WriteCellOpts(cell.FgColor(cell.ColorGreen))); err != nil {
+		panic(err)
+	}
+	if failure := lc.failure(); failure > 0 {
+		if err := t.Write(fmt.Sprintf("\nlast failure #%s", numberWithComma(failure)), text.WriteCellOpts(cell.FgColor(cell.ColorRed))); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// CometBFT constants of the signed commits
+const (
+	blockIDFlagCommit = 2
+	precommitType     = 2
+	ed25519KeyType    = "tendermint/PubKeyEd25519"
+	secp256k1KeyType  = "tendermint/PubKeySecp256k1"
+)
+
+// errNotEnoughPower is returned when the validators signing a commit don't
+// have enough voting power.
+var errNotEnoughPower = errors.New("not enough voting power signed")
+
+// errNotTrusted is returned when too few trusted validators signed a header
+// that skips blocks, the blocks in between are verified first.
+var errNotTrusted = errors.New("not signed by +1/3 of the trusted validators")
+
+// lightClient verifies the headers of the new blocks like a CometBFT light
+// client: each header must be signed by +2/3 of its validator set, which in
+// turn must be announced by the previous trusted header or signed by +1/3 of
+// its validators. The trusted header must be within the trusting period.
+type lightClient struct {
+	chainID            string
+	trustPeriod        time.Duration
+	trustedHeight      int64
+	trustedTime        time.Time
+	trustedHash        []byte
+	nextValidatorsHash []byte
+	trustedValidators  []lightValidator
+	// lastFailure is read by the JSON API while the blocks are verified
+	mu          sync.Mutex
+	lastFailure int64
+}
+
+// lightValidator is a validator of a validator set.
+type lightValidator struct {
+	address []byte
+	keyType string
+	pubKey  []byte
+	power   int64
+}
+
+// newLightClient creates a light client trusting either the genesis validators
+// or a block given as <height>:<hash>, which must be newer than the trusting
+// period.
+func newLightClient(trust string, trustPeriod time.Duration, genesisInfo gjson.Result) (*lightClient, error) {
+	lc, err := trustRoot(trust, genesisInfo)
+	if err != nil {
+		return nil, err
+	}
+	if age := time.Since(lc.trustedTime); age > trustPeriod {
+		return nil, fmt.Errorf("the trusted block is %s old, older than the trusting period of %s, trust a newer block", formatDuration(age), formatDuration(trustPeriod))
+	}
+	lc.trustPeriod = trustPeriod
+
+	return lc, nil
+}
+
+// trustRoot reads the genesis or the trusted block.
+func trustRoot(trust string, genesisInfo gjson.Result) (*lightClient, error) {
+	if trust == "genesis" {
+		genesis := genesisInfo.Get("result.genesis")
+		validators, err := parseValidators(genesis.Get("validators").Array(), "power")
+		if err != nil {
+			return nil, err
+		}
+		if len(validators) == 0 {
+			return nil, errors.New("the genesis has no validators, trust a block with -t <height>:<hash> instead")
+		}
+		initialHeight := genesis.Get("initial_height").Int()
+		if initialHeight == 0 {
+			initialHeight = 1
+		}
+		genesisTime, err := time.Parse(time.RFC3339Nano, genesis.Get("genesis_time").String())
+		if err != nil {
+			return nil, err
+		}
+
+		return &lightClient{
+			chainID:            genesis.Get("chain_id").String(),
+			trustedHeight:      initialHeight - 1,
+			trustedTime:        genesisTime,
+			nextValidatorsHash: validatorSetHash(validators),
+			trustedValidators:  validators,
+		}, nil
+	}
+
+	parts := strings.Split(trust, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid trusted block %q, expected <height>:<hash> or genesis", trust)
+	}
+	height, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted height %q", parts[0])
+	}
+	trustedHash, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted hash %q", parts[1])
+	}
+
+	header, err := queryHeader(height)
+	if err != nil {
+		return nil, err
+	}
+	headerTime, err := time.Parse(time.RFC3339Nano, header.Get("time").String())
+	if err != nil {
+		return nil, err
+	}
+	hash, err := headerHash(header)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hash, trustedHash) {
+		return nil, fmt.Errorf("the block %d has the hash %X, not the trusted one", height, hash)
+	}
+	validators, err := queryValidators(height)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(validatorSetHash(validators), hexField(header, "validators_hash")) {
+		return nil, fmt.Errorf("the validator set of the block %d doesn't match its header", height)
+	}
+
+	return &lightClient{
+		chainID:            header.Get("chain_id").String(),
+		trustedHeight:      height,
+		trustedTime:        headerTime,
+		trustedHash:        hash,
+		nextValidatorsHash: hexField(header, "next_validators_hash"),
+		trustedValidators:  validators,
+	}, nil
+}
+
+// verify verifies a header received with a NewBlock event and trusts it once
+// it's verified.
+func (lc *lightClient) verify(header gjson.Result) error {
+	var err error
+	if header.Get("height").Int() <= lc.trustedHeight {
+		err = lc.verifyTrusted(header)
+	} else {
+		err = lc.verifyBisecting(header)
+	}
+	if err != nil {
+		lc.mu.Lock()
+		lc.lastFailure = header.Get("height").Int()
+		lc.mu.Unlock()
+	}
+
+	return err
+}
+
+// verifyTrusted checks a header delivered again, e.g. after a reconnect or
+// when the events switch to polling, against the trusted one. The headers
+// below it were trusted before.
+func (lc *lightClient) verifyTrusted(header gjson.Result) error {
+	height := header.Get("height").Int()
+	if height != lc.trustedHeight || lc.trustedHash == nil {
+		return nil
+	}
+
+	hash, err := headerHash(header)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, lc.trustedHash) {
+		return fmt.Errorf("the block %d has the hash %X, not the trusted one", height, hash)
+	}
+
+	return nil
+}
+
+// failure returns the height of the last header that failed the
+// verification, 0 if none did.
+func (lc *lightClient) failure() int64 {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	return lc.lastFailure
+}
+
+// verifyBisecting verifies a header, when the trusted validators can't vouch
+// for it the header halfway to it is verified first, like the bisection of
+// the CometBFT light client catches up from an old trusted header.
+func (lc *lightClient) verifyBisecting(header gjson.Result) error {
+	height := header.Get("height").Int()
+	for {
+		err := lc.verifyHeader(header)
+		if !errors.Is(err, errNotTrusted) {
+			return err
+		}
+
+		// the header skipped blocks, so the pivot is above the trusted height
+		pivot, err := queryHeader((lc.trustedHeight + height) / 2)
+		if err != nil {
+			return err
+		}
+		if err := lc.verifyBisecting(pivot); err != nil {
+			return err
+		}
+	}
+}
+
+// queryHeader queries the header of a height.
+func queryHeader(height int64) (gjson.Result, error) {
+	commitRPC, err := getResultFromRPC(fmt.Sprintf("commit?height=%d", height))
+	if err != nil {
+		return gjson.Result{}, err
+	}
+
+	return gjson.Get(commitRPC, "result.signed_header.header"), nil
+}
+
+// verifyHeader checks the header against its commit and validator set.
+func (lc *lightClient) verifyHeader(header gjson.Result) error {
+	height := header.Get("height").Int()
+	if height <= lc.trustedHeight {
+		return fmt.Errorf("block %d is below the trusted height %d", height, lc.trustedHeight)
+	}
+	if header.Get("chain_id").String() != lc.chainID {
+		return fmt.Errorf("chain id %s isn't the trusted %s", header.Get("chain_id").String(), lc.chainID)
+	}
+	if time.Since(lc.trustedTime) > lc.trustPeriod {
+		return fmt.Errorf("the trusted block %d expired, restart with a newer one", lc.trustedHeight)
+	}
+	headerTime, err := time.Parse(time.RFC3339Nano, header.Get("time").String())
+	if err != nil {
+		return err
+	}
+
+	hash, err := headerHash(header)
+	if err != nil {
+		return err
+	}
+	commitRPC, err := getResultFromRPC(fmt.Sprintf("commit?height=%d", height))
+	if err != nil {
+		return err
+	}
+	commit := gjson.Get(commitRPC, "result.signed_header.commit")
+	if commit.Get("height").Int() != height || !bytes.Equal(hexField(commit, "block_id.hash"), hash) {
+		return errors.New("header hash doesn't match the commit")
+	}
+
+	validators, err := queryValidators(height)
+	if err != nil {
+		return err
+	}
+	validatorsHash := hexField(header, "validators_hash")
+	if !bytes.Equal(validatorSetHash(validators), validatorsHash) {
+		return errors.New("validator set doesn't match the header")
+	}
+
+	if height == lc.trustedHeight+1 {
+		// the trusted header announced this validator set
+		if !bytes.Equal(validatorsHash, lc.nextValidatorsHash) {
+			return errors.New("validator set wasn't announced by the trusted header")
+		}
+	} else {
+		// blocks were skipped, +1/3 of the trusted validators must have signed
+		err := verifyCommit(lc.chainID, commit, lc.trustedValidators, false, 1, 3)
+		if errors.Is(err, errNotEnoughPower) {
+			return errNotTrusted
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := verifyCommit(lc.chainID, commit, validators, true, 2, 3); err != nil {
+		return err
+	}
+
+	lc.trustedHeight = height
+	lc.trustedTime = headerTime
+	lc.trustedHash = hash
+	lc.nextValidatorsHash = hexField(header, "next_validators_hash")
+	lc.trustedValidators = validators
+
+	return nil
+}
+
+// verifyCommit checks the signatures of a commit and that the validators
+// signing it have more than num/den of the voting power. The signatures of a
+// commit are ordered like its validator set, otherwise the validators are
+// matched by address.
+func verifyCommit(chainID string, commit gjson.Result, validators []lightValidator, byIndex bool, num, den int64) error {
+	total := int64(0)
+	byAddress := map[string]lightValidator{}
+	for _, v := range validators {
+		total += v.power
+		byAddress[string(v.address)] = v
+	}
+
+	signed := int64(0)
+	seen := map[string]bool{}
+	for i, sig := range commit.Get("signatures").Array() {
+		if sig.Get("block_id_flag").Int() != blockIDFlagCommit {
+			continue
+		}
+		address := hexField(sig, "validator_address")
+		v, ok := byAddress[string(address)]
+		if byIndex {
+			if i >= len(validators) || !bytes.Equal(validators[i].address, address) {
+				return fmt.Errorf("signature %d isn't of the validator at its index", i)
+			}
+			v, ok = validators[i], true
+		}
+		if !ok || seen[string(address)] {
+			continue
+		}
+		seen[string(address)] = true
+
+		if v.keyType != ed25519KeyType || len(v.pubKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("can't verify %s signatures", v.keyType)
+		}
+		signature, err := base64.StdEncoding.DecodeString(sig.Get("signature").String())
+		if err != nil {
+			return err
+		}
+		signBytes, err := voteSignBytes(chainID, commit, sig.Get("timestamp").String())
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(ed25519.PublicKey(v.pubKey), signBytes, signature) {
+			return fmt.Errorf("invalid signature of validator %X", address)
+		}
+		signed += v.power
+	}
+
+	if signed*den <= total*num {
+		return fmt.Errorf("%w, only %d of %d", errNotEnoughPower, signed, total)
+	}
+
+	return nil
+}
+
+// queryValidators queries the validator set of a height.
+func queryValidators(height int64) ([]lightValidator, error) {
+	results, err := queryValidatorSet(height)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseValidators(results, "voting_power")
+}
+
+// parseValidators reads validators of the RPC or the genesis in the order of
+// their validator set, powerField names their voting power.
+func parseValidators(results []gjson.Result, powerField string) ([]lightValidator, error) {
+	var validators []lightValidator
+	for _, r := range results {
+		pubKey, err := base64.StdEncoding.DecodeString(r.Get("pub_key.value").String())
+		if err != nil {
+			return nil, err
+		}
+		v := lightValidator{
+			keyType: r.Get("pub_key.type").String(),
+			pubKey:  pubKey,
+			power:   r.Get(powerField).Int(),
+		}
+		v.address = hexField(r, "address")
+		if v.keyType == ed25519KeyType {
+			sum := sha256.Sum256(pubKey)
+			v.address = sum[:20]
+		}
+		validators = append(validators, v)
+	}
+
+	// validator sets are sorted by voting power and address
+	sort.SliceStable(validators, func(i, j int) bool {
+		if validators[i].power != validators[j].power {
+			return validators[i].power > validators[j].power
+		}
+		return bytes.Compare(validators[i].address, validators[j].address) < 0
+	})
+
+	return validators, nil
+}
+
+// validatorSetHash calculates the merkle root of the validators, encoded as
+// tendermint.types.SimpleValidator.
+func validatorSetHash(validators []lightValidator) []byte {
+	var items [][]byte
+	for _, v := range validators {
+		// tendermint.crypto.PublicKey is a oneof of ed25519 and secp256k1
+		keyField := 1
+		if v.keyType == secp256k1KeyType {
+			keyField = 2
+		}
+		pubKey := protoAppendBytes(nil, keyField, v.pubKey)
+
+		var item []byte
+		item = protoAppendMessage(item, 1, pubKey)
+		item = protoAppendVarint(item, 2, uint64(v.power))
+		items = append(items, item)
+	}
+
+	return merkleRoot(items)
+}
+
+// headerHash calculates the hash of a header, the merkle root of its protobuf
+// encoded fields.
+func headerHash(header gjson.Result) ([]byte, error) {
+	t, err := time.Parse(time.RFC3339Nano, header.Get("time").String())
+	if err != nil {
+		return nil, err
+	}
+
+	var version []byte
+	version = protoAppendVarint(version, 1, uint64(header.Get("version.block").Int()))
+	version = protoAppendVarint(version, 2, uint64(header.Get("version.app").Int()))
+
+	items := [][]byte{
+		version,
+		protoAppendBytes(nil, 1, []byte(header.Get("chain_id").String())),
+		protoAppendVarint(nil, 1, uint64(header.Get("height").Int())),
+		protoTimestamp(t),
+		blockID(header.Get("last_block_id")),
+	}
+	for _, field := range []string{
+		"last_commit_hash",
+		"data_hash",
+		"validators_hash",
+		"next_validators_hash",
+		"consensus_hash",
+		"app_hash",
+		"last_results_hash",
+		"evidence_hash",
+		"proposer_address",
+	} {
+		// google.protobuf.BytesValue
+		items = append(items, protoAppendBytes(nil, 1, hexField(header, field)))
+	}
+
+	return merkleRoot(items), nil
+}
+
+// voteSignBytes encodes the length delimited tendermint.types.CanonicalVote a
+// validator signed for a commit.
+func voteSignBytes(chainID string, commit gjson.Result, timestamp string) ([]byte, error) {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	var vote []byte
+	vote = protoAppendVarint(vote, 1, precommitType)
+	vote = protoAppendFixed64(vote, 2, uint64(commit.Get("height").Int()))
+	vote = protoAppendFixed64(vote, 3, uint64(commit.Get("round").Int()))
+	// votes for nil have no block id
+	if id := commit.Get("block_id"); len(hexField(id, "hash")) > 0 {
+		vote = protoAppendMessage(vote, 4, blockID(id))
+	}
+	vote = protoAppendMessage(vote, 5, protoTimestamp(t))
+	vote = protoAppendBytes(vote, 6, []byte(chainID))
+
+	return append(appendVarint(nil, uint64(len(vote))), vote...), nil
+}
+
+// blockID encodes a tendermint.types.BlockID.
+func blockID(id gjson.Result) []byte {
+	var parts []byte
+	parts = protoAppendVarint(parts, 1, uint64(id.Get("parts.total").Int()))
+	parts = protoAppendBytes(parts, 2, hexField(id, "parts.hash"))
+
+	var b []byte
+	b = protoAppendBytes(b, 1, hexField(id, "hash"))
+
+	return protoAppendMessage(b, 2, parts)
+}
+
+// protoTimestamp encodes a google.protobuf.Timestamp.
+func protoTimestamp(t time.Time) []byte {
+	var b []byte
+	b = protoAppendVarint(b, 1, uint64(t.Unix()))
+
+	return protoAppendVarint(b, 2, uint64(t.Nanosecond()))
+}
+
+// merkleRoot calculates the RFC 6962 merkle root CometBFT uses.
+func merkleRoot(items [][]byte) []byte {
+	switch len(items) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		sum := sha256.Sum256(append([]byte{0}, items[0]...))
+		return sum[:]
+	}
+
+	// split at the largest power of 2 below the number of items
+	k := 1
+	for k*2 < len(items) {
+		k *= 2
+	}
+	inner := append([]byte{1}, merkleRoot(items[:k])...)
+	inner = append(inner, merkleRoot(items[k:])...)
+	sum := sha256.Sum256(inner)
+
+	return sum[:]
+}
+
+// hexField decodes a hex encoded field, invalid ones are empty.
+func hexField(r gjson.Result, path string) []byte {
+	b, err := hex.DecodeString(r.Get(path).String())
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	// the test vectors of RFC 6962, the leaves are added one by one
+	leaves := []string{"", "00", "10", "2021", "3031", "40414243", "5051525354555657", "606162636465666768696a6b6c6d6e6f"}
+	roots := []string{
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+		"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+		"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+	}
+
+	for n, want := range roots {
+		var items [][]byte
+		for _, leaf := range leaves[:n] {
+			item, _ := hex.DecodeString(leaf)
+			items = append(items, item)
+		}
+		if got := hex.EncodeToString(merkleRoot(items)); got != want {
+			t.Errorf("merkleRoot of %d leaves = %s, want %s", n, got, want)
+		}
+	}
+}
+
+// sumHex is the hex encoded SHA-256 of s, truncated to size bytes.
+func sumHex(s string, size int) string {
+	sum := sha256.Sum256([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:size]))
+}
+
+// cometHeader is the header of TestHeaderHash of CometBFT.
+func cometHeader() gjson.Result {
+	zeros := strings.Repeat("00", 32)
+	return gjson.Parse(fmt.Sprintf(`{
+		"version": {"block": "1", "app": "2"},
+		"chain_id": "chainId",
+		"height": "3",
+		"time": "2019-10-13T16:14:44Z",
+		"last_block_id": {"hash": "%s", "parts": {"total": 6, "hash": "%s"}},
+		"last_commit_hash": "%s",
+		"data_hash": "%s",
+		"validators_hash": "%s",
+		"next_validators_hash": "%s",
+		"consensus_hash": "%s",
+		"app_hash": "%s",
+		"last_results_hash": "%s",
+		"evidence_hash": "%s",
+		"proposer_address": "%s"
+	}`,
+		zeros, zeros,
+		sumHex("last_commit_hash", 32),
+		sumHex("data_hash", 32),
+		sumHex("validators_hash", 32),
+		sumHex("next_validators_hash", 32),
+		sumHex("consensus_hash", 32),
+		sumHex("app_hash", 32),
+		sumHex("last_results_hash", 32),
+		sumHex("evidence_hash", 32),
+		sumHex("proposer_address", 20),
+	))
+}
+
+func TestHeaderHash(t *testing.T) {
+	header := cometHeader()
+
+	tests := []struct {
+		name   string
+		header gjson.Result
+		want   string
+		valid  bool
+	}{
+		{"known hash", header, "F740121F553B5418C3EFBD343C2DBFE9E007BB67B0D020A0741374BAB65242A4", true},
+		{"invalid time", gjson.Parse(`{"time": "yesterday"}`), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := headerHash(tt.header)
+			if (err == nil) != tt.valid {
+				t.Fatalf("headerHash error = %v, want valid %v", err, tt.valid)
+			}
+			if got := strings.ToUpper(hex.EncodeToString(hash)); tt.valid && got != tt.want {
+				t.Errorf("headerHash = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVoteSignBytes(t *testing.T) {
+	height := []byte{0x11, 0x1, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+	round := []byte{0x19, 0x1, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0}
+	// 0001-01-01T00:00:00Z
+	zeroTime := []byte{0x2a, 0xb, 0x8, 0x80, 0x92, 0xb8, 0xc3, 0x98, 0xfe, 0xff, 0xff, 0xff, 0x1}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	tests := []struct {
+		name      string
+		chainID   string
+		commit    string
+		timestamp string
+		want      []byte
+		valid     bool
+	}{
+		// the precommit vectors of TestVoteSignBytesTestVectors of CometBFT
+		{
+			"nil block",
+			"",
+			`{"height": "1", "round": 1, "block_id": {"hash": "", "parts": {"total": 0, "hash": ""}}}`,
+			"0001-01-01T00:00:00Z",
+			join([]byte{0x21, 0x8, 0x2}, height, round, zeroTime),
+			true,
+		},
+		{
+			"chain id",
+			"test_chain_id",
+			`{"height": "1", "round": 1, "block_id": {}}`,
+			"0001-01-01T00:00:00Z",
+			join([]byte{0x30, 0x8, 0x2}, height, round, zeroTime, []byte{0x32, 0xd}, []byte("test_chain_id")),
+			true,
+		},
+		{
+			"block id",
+			"",
+			`{"height": "1", "round": 1, "block_id": {"hash": "AB", "parts": {"total": 1, "hash": "CD"}}}`,
+			"0001-01-01T00:00:00Z",
+			join([]byte{0x2d, 0x8, 0x2}, height, round, []byte{0x22, 0xa, 0xa, 0x1, 0xab, 0x12, 0x5, 0x8, 0x1, 0x12, 0x1, 0xcd}, zeroTime),
+			true,
+		},
+		{"invalid timestamp", "", `{"height": "1"}`, "yesterday", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := voteSignBytes(tt.chainID, gjson.Parse(tt.commit), tt.timestamp)
+			if (err == nil) != tt.valid {
+				t.Fatalf("voteSignBytes error = %v, want valid %v", err, tt.valid)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("voteSignBytes = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyCommit(t *testing.T) {
+	validators := []lightValidator{
+		{address: []byte{1}, keyType: ed25519KeyType, power: 10},
+		{address: []byte{2}, keyType: ed25519KeyType, power: 10},
+		{address: []byte{3}, keyType: ed25519KeyType, power: 10},
+	}
+
+	// absent signatures aren't verified, so none signed
+	commit := gjson.Parse(`{"height": "1", "round": 0, "signatures": [{"block_id_flag": 1}, {"block_id_flag": 1}, {"block_id_flag": 1}]}`)
+	if err := verifyCommit("chain", commit, validators, true, 1, 3); !errors.Is(err, errNotEnoughPower) {
+		t.Errorf("verifyCommit without signatures error = %v, want %v", err, errNotEnoughPower)
+	}
+
+	// a signature at the index of another validator
+	commit = gjson.Parse(`{"height": "1", "round": 0, "signatures": [{"block_id_flag": 2, "validator_address": "02"}]}`)
+	if err := verifyCommit("chain", commit, validators, true, 2, 3); err == nil {
+		t.Error("verifyCommit with a misplaced signature didn't fail")
+	}
+}
+
+func TestVerifyTrusted(t *testing.T) {
+	trustedHash, err := hex.DecodeString("F740121F553B5418C3EFBD343C2DBFE9E007BB67B0D020A0741374BAB65242A4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forked := strings.Replace(cometHeader().Raw, sumHex("app_hash", 32), sumHex("forked", 32), 1)
+	below := strings.Replace(cometHeader().Raw, `"height": "3"`, `"height": "2"`, 1)
+
+	tests := []struct {
+		name    string
+		header  gjson.Result
+		valid   bool
+		failure int64
+	}{
+		{"trusted header delivered again", cometHeader(), true, 0},
+		{"header below the trusted one", gjson.Parse(below), true, 0},
+		{"other header at the trusted height", gjson.Parse(forked), false, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := &lightClient{chainID: "chainId", trustedHeight: 3, trustedHash: trustedHash}
+			err := lc.verify(tt.header)
+			if (err == nil) != tt.valid {
+				t.Fatalf("verify error = %v, want valid %v", err, tt.valid)
+			}
+			if got := lc.failure(); got != tt.failure {
+				t.Errorf("failure = %d, want %d", got, tt.failure)
+			}
+			if lc.trustedHeight != 3 {
+				t.Errorf("trusted height changed to %d", lc.trustedHeight)
+			}
+		})
+	}
+}
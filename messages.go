@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/barchart"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// messageWindow is the time span the message type chart covers.
+const messageWindow = 10 * time.Minute
+
+// messageBars is the number of message types shown in the chart.
+const messageBars = 8
+
+// Messages counts the message types of the confirmed transactions
+type Messages struct {
+	mu     sync.Mutex
+	window []messageSeen
+	totals map[string]*messageCounts
+}
+
+// messageSeen is a message of a confirmed transaction
+type messageSeen struct {
+	at      time.Time
+	msgType string
+}
+
+// messageCounts are the messages of a type by the result of their transaction
+type messageCounts struct {
+	succeeded int
+	failed    int
+}
+
+// messageStats are the counts of a message type.
+type messageStats struct {
+	msgType  string
+	inWindow int
+	messageCounts
+}
+
+// count counts the messages of a Tx event received over the websocket.
+func (m *Messages) count(message string) {
+	txResult := gjson.Get(message, "result.data.value.TxResult")
+	tx, err := decodeTx(txResult.Get("tx").String())
+	if err != nil {
+		return
+	}
+	failed := txResult.Get("result.code").Int() != 0
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.totals == nil {
+		m.totals = map[string]*messageCounts{}
+	}
+
+	now := time.Now()
+	for _, msgType := range tx.messages {
+		m.window = append(m.window, messageSeen{at: now, msgType: msgType})
+		c, ok := m.totals[msgType]
+		if !ok {
+			c = &messageCounts{}
+			m.totals[msgType] = c
+		}
+		if failed {
+			c.failed++
+		} else {
+			c.succeeded++
+		}
+	}
+}
+
+// stats returns the counts of all message types seen, the most frequent in
+// the window first.
+func (m *Messages) stats() []messageStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// drop the messages that left the window
+	cutoff := time.Now().Add(-messageWindow)
+	i := 0
+	for i < len(m.window) && m.window[i].at.Before(cutoff) {
+		i++
+	}
+	m.window = m.window[i:]
+
+	inWindow := map[string]int{}
+	for _, seen := range m.window {
+		inWindow[seen.msgType]++
+	}
+
+	var stats []messageStats
+	for msgType, c := range m.totals {
+		stats = append(stats, messageStats{msgType: msgType, inWindow: inWindow[msgType], messageCounts: *c})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].inWindow != stats[j].inWindow {
+			return stats[i].inWindow > stats[j].inWindow
+		}
+		if total := stats[i].succeeded + stats[i].failed; total != stats[j].succeeded+stats[j].failed {
+			return total > stats[j].succeeded+stats[j].failed
+		}
+		return stats[i].msgType < stats[j].msgType
+	})
+
+	return stats
+}
+
+// messagesPage creates the page breaking down the confirmed transactions by
+// their message types.
+func messagesPage(ctx context.Context, info Info, delay time.Duration) page {
+	messageChart, err := barchart.New(
+		barchart.ShowValues(),
+		barchart.BarColors([]cell.Color{cell.ColorNumber(2)}),
+		barchart.ValueColors([]cell.Color{cell.ColorBlack}),
+		barchart.LabelColors([]cell.Color{cell.ColorNumber(2)}),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	messageTableWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := messageTableWidget.Write("Message types will appear as soon as transactions are confirmed in a block.\n"); err != nil {
+		panic(err)
+	}
+
+	go writeMessages(ctx, info, messageChart, messageTableWidget, delay)
+
+	return page{
+		name: "Messages",
+		layout: []container.Option{
+			container.SplitHorizontal(
+				container.Top(
+					container.Border(linestyle.Light),
+					container.BorderTitle(fmt.Sprintf("Message Types (last %d minutes)", int(messageWindow.Minutes()))),
+					container.PlaceWidget(messageChart),
+				),
+				container.Bottom(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Message Types since gex started"),
+					container.PlaceWidget(messageTableWidget),
+				),
+				container.SplitPercent(50),
+			),
+		},
+	}
+}
+
+// writeMessages writes the message type chart and table.
+// Exits when the context expires.
+func writeMessages(ctx context.Context, info Info, bc *barchart.BarChart, t *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			stats := info.messages.stats()
+			if len(stats) == 0 {
+				continue
+			}
+
+			var values []int
+			var labels []string
+			max := 0
+			for _, s := range stats {
+				if len(values) == messageBars || s.inWindow == 0 {
+					break
+				}
+				values = append(values, s.inWindow)
+				labels = append(labels, shortMsgType(s.msgType))
+				if s.inWindow > max {
+					max = s.inWindow
+				}
+			}
+			if len(values) > 0 {
+				if err := bc.Values(values, max, barchart.Labels(labels)); err != nil {
+					panic(err)
+				}
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "%-60s %8s %8s %8s %8s %8s\n", "TYPE", "WINDOW", "TOTAL", "OK", "FAILED", "SUCCESS")
+			for _, s := range stats {
+				total := s.succeeded + s.failed
+				fmt.Fprintf(&b, "%-60s %8d %8d %8d %8d %7.2f%%\n",
+					shorten(s.msgType, 60),
+					s.inWindow,
+					total,
+					s.succeeded,
+					s.failed,
+					float64(s.succeeded)/float64(total)*100,
+				)
+			}
+
+			t.Reset()
+			if err := t.Write(b.String()); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// shortMsgType cuts the package off a message type URL,
+// e.g. /cosmos.bank.v1beta1.MsgSend becomes MsgSend.
+func shortMsgType(msgType string) string {
+	return msgType[strings.LastIndex(msgType, ".")+1:]
+}
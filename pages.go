@@ -0,0 +1,729 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/donut"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// pageContainerID identifies the container that holds the active page.
+const pageContainerID = "page"
+
+// page describes a view of the dashboard.
+// A layout with a split at its root must set the split size explicitly since
+// the container keeps the size of the previously shown page otherwise.
+type page struct {
+	name   string
+	layout []container.Option
+}
+
+// pager switches between the pages and renders the status bar.
+type pager struct {
+	c      *container.Container
+	status *text.Text
+	pages  []page
+	active int
+	hints  string
+	width  func() int
+}
+
+// newPager creates a pager for the given pages, showing them in the container
+// with the pageContainerID. The width returns the columns of the status bar.
+func newPager(c *container.Container, status *text.Text, pages []page, width func() int) *pager {
+	return &pager{
+		c:      c,
+		status: status,
+		pages:  pages,
+		width:  width,
+	}
+}
+
+// show makes the i-th page the active one.
+func (p *pager) show(i int) error {
+	if i < 0 || i >= len(p.pages) {
+		return fmt.Errorf("page %d doesn't exist", i+1)
+	}
+
+	// reset the decoration a previous page might have left on the container
+	opts := append([]container.Option{
+		container.Border(linestyle.None),
+		container.BorderTitle(""),
+	}, p.pages[i].layout...)
+	if err := p.c.Update(pageContainerID, opts...); err != nil {
+		return err
+	}
+	p.active = i
+
+	return p.writeStatus()
+}
+
+// next shows the page after the active one.
+func (p *pager) next() {
+	if err := p.show((p.active + 1) % len(p.pages)); err != nil {
+		panic(err)
+	}
+}
+
+// prev shows the page before the active one.
+func (p *pager) prev() {
+	if err := p.show((p.active + len(p.pages) - 1) % len(p.pages)); err != nil {
+		panic(err)
+	}
+}
+
+// bindings returns the number key bindings for the first ten pages, the
+// tenth page is bound to 0.
+func (p *pager) bindings() keyBindings {
+	var kb keyBindings
+	for i := range p.pages {
+		key, ok := pageKey(i)
+		if !ok {
+			break
+		}
+		i := i
+		kb = append(kb, keyBinding{
+			keys: []keyboard.Key{key},
+			action: func() {
+				if err := p.show(i); err != nil {
+					panic(err)
+				}
+			},
+		})
+	}
+
+	return kb
+}
+
+// pageKey returns the number key of the i-th page, the pages after the tenth
+// don't have one.
+func pageKey(i int) (keyboard.Key, bool) {
+	switch {
+	case i < 9:
+		return keyboard.Key('1' + i), true
+	case i == 9:
+		return '0', true
+	default:
+		return 0, false
+	}
+}
+
+// find returns the index of the page with the name.
+func (p *pager) find(name string) int {
+	for i, pg := range p.pages {
+		if pg.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// writeStatus writes the page tabs and the key hints to the status bar. The
+// tabs scroll when they don't fit, keeping the active one in view.
+func (p *pager) writeStatus() error {
+	labels := make([]string, len(p.pages))
+	widths := make([]int, len(p.pages))
+	for i, pg := range p.pages {
+		labels[i] = fmt.Sprintf(" %s ", pg.name)
+		if key, ok := pageKey(i); ok {
+			labels[i] = fmt.Sprintf(" %c %s ", key, pg.name)
+		}
+		widths[i] = utf8.RuneCountInString(labels[i])
+	}
+	first, last := tabWindow(widths, p.active, p.width())
+
+	p.status.Reset()
+	if first > 0 {
+		if err := p.status.Write("‹"); err != nil {
+			return err
+		}
+	}
+	for i := first; i <= last; i++ {
+		var err error
+		if i == p.active {
+			err = p.status.Write(labels[i], text.WriteCellOpts(cell.FgColor(cell.ColorBlack), cell.BgColor(cell.ColorNumber(2))))
+		} else {
+			err = p.status.Write(labels[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if last < len(labels)-1 {
+		if err := p.status.Write("›"); err != nil {
+			return err
+		}
+	}
+
+	return p.status.Write("\n "+p.hints, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(8))))
+}
+
+// tabWindow returns the first and the last of the tabs with the widths that
+// fit into the width along with the active tab and the markers of the hidden
+// tabs on either side.
+func tabWindow(widths []int, active, width int) (int, int) {
+	fits := func(first, last int) bool {
+		used := 0
+		if first > 0 {
+			used++
+		}
+		if last < len(widths)-1 {
+			used++
+		}
+		for _, w := range widths[first : last+1] {
+			used += w
+		}
+		return used <= width
+	}
+
+	first := 0
+	for first < active && !fits(first, active) {
+		first++
+	}
+	last := active
+	for last < len(widths)-1 && fits(first, last+1) {
+		last++
+	}
+
+	return first, last
+}
+
+// BLOCKS PAGE
+
+// blocksPage creates the page listing the most recent blocks.
+func blocksPage(ctx context.Context, delay time.Duration) page {
+	recentBlocksWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := recentBlocksWidget.Write("⌛ loading"); err != nil {
+		panic(err)
+	}
+
+	go writeRecentBlocks(ctx, recentBlocksWidget, delay)
+
+	return page{
+		name: "Blocks",
+		layout: []container.Option{
+			container.Border(linestyle.Light),
+			container.BorderTitle("Recent Blocks"),
+			container.PlaceWidget(recentBlocksWidget),
+		},
+	}
+}
+
+// writeRecentBlocks writes the latest blocks known to the node.
+// Exits when the context expires.
+func writeRecentBlocks(ctx context.Context, t *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			blockchainRPC, err := getFromRPC("blockchain")
+			if err != nil {
+				continue
+			}
+			metas := gjson.Get(blockchainRPC, "result.block_metas")
+			if !metas.Exists() {
+				continue
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "%-12s %-22s %5s  %-14s %s\n", "HEIGHT", "TIME", "TXS", "PROPOSER", "HASH")
+			for _, meta := range metas.Array() {
+				fmt.Fprintf(&b, "%-12s %-22s %5d  %-14s %s\n",
+					numberWithComma(meta.Get("header.height").Int()),
+					formatTime(meta.Get("header.time").String()),
+					meta.Get("num_txs").Int(),
+					shorten(meta.Get("header.proposer_address").String(), 12),
+					meta.Get("block_id.hash").String(),
+				)
+			}
+
+			t.Reset()
+			if err := t.Write(b.String()); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// TRANSACTIONS PAGE
+
+// transactionsPage creates the page listing the confirmed transactions next to
+// their failures. The list widget is fed by writeTransactions.
+func transactionsPage(ctx context.Context, info Info, list *text.Text, delay time.Duration) page {
+	failuresWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := failuresWidget.Write("No failed transactions yet.\n"); err != nil {
+		panic(err)
+	}
+
+	go writeFailures(ctx, info, failuresWidget, delay)
+
+	return page{
+		name: "Transactions",
+		layout: []container.Option{
+			container.SplitVertical(
+				container.Left(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Confirmed Transactions (time, height, hash, result, gas used / wanted)"),
+					container.PlaceWidget(list),
+				),
+				container.Right(
+					container.Border(linestyle.Light),
+					container.BorderTitle(fmt.Sprintf("Failures (last %d minutes)", int(failureWindow.Minutes()))),
+					container.PlaceWidget(failuresWidget),
+				),
+				container.SplitPercent(65),
+			),
+		},
+	}
+}
+
+// txSummary formats a Tx event received over the websocket as a single line.
+func txSummary(message string, received time.Time) string {
+	txResult := gjson.Get(message, "result.data.value.TxResult")
+	status, _ := txStatus(message)
+
+	return fmt.Sprintf("%s  #%-10s %-16s %-30s %s / %s (%.0f%%)\n",
+		received.Format("03:04:05 PM"),
+		numberWithComma(txResult.Get("height").Int()),
+		shorten(gjson.Get(message, `result.events.tx\.hash.0`).String(), 16),
+		shorten(status, 30),
+		numberWithComma(txResult.Get("result.gas_used").Int()),
+		numberWithComma(txResult.Get("result.gas_wanted").Int()),
+		gasEfficiency(txResult.Get("result.gas_used").Int(), txResult.Get("result.gas_wanted").Int()),
+	)
+}
+
+// VALIDATORS PAGE
+
+// validatorsPage creates the page listing the active validator set.
+func validatorsPage(ctx context.Context, delay time.Duration) page {
+	validatorListWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := validatorListWidget.Write("⌛ loading"); err != nil {
+		panic(err)
+	}
+
+	go writeValidatorList(ctx, validatorListWidget, delay)
+
+	return page{
+		name: "Validators",
+		layout: []container.Option{
+			container.Border(linestyle.Light),
+			container.BorderTitle("Validator Set"),
+			container.PlaceWidget(validatorListWidget),
+		},
+	}
+}
+
+// writeValidatorList writes the validators with their voting power, joined
+// with x/staking when the Cosmos SDK API is configured.
+// Exits when the context expires.
+func writeValidatorList(ctx context.Context, t *text.Text, delay time.Duration) {
+	self := &selfDelegations{}
+
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			validators, err := queryValidatorSet(0)
+			if err != nil {
+				continue
+			}
+
+			list := validatorSetTable(validators)
+			if *givenAPI != "" {
+				staking, err := stakingTable(validators, self)
+				if err != nil {
+					staking = fmt.Sprintf("✖️ %s\n\n%s", err, list)
+				}
+				list = staking
+			}
+
+			t.Reset()
+			if err := t.Write(list); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// queryValidatorSet queries all pages of the validator set of a height, the
+// latest one for 0.
+func queryValidatorSet(height int64) ([]gjson.Result, error) {
+	query := "validators?"
+	if height > 0 {
+		query = fmt.Sprintf("validators?height=%d&", height)
+	}
+
+	var validators []gjson.Result
+	for page := 1; ; page++ {
+		validatorsRPC, err := getResultFromRPC(fmt.Sprintf("%spage=%d&per_page=100", query, page))
+		if err != nil {
+			return nil, err
+		}
+		results := gjson.Get(validatorsRPC, "result.validators").Array()
+		validators = append(validators, results...)
+		if len(results) == 0 || int64(len(validators)) >= gjson.Get(validatorsRPC, "result.total").Int() {
+			return validators, nil
+		}
+	}
+}
+
+// validatorSetTable lists the CometBFT validator set.
+func validatorSetTable(validators []gjson.Result) string {
+	totalPower := int64(0)
+	for _, v := range validators {
+		totalPower += v.Get("voting_power").Int()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-42s %18s %8s %18s\n", "ADDRESS", "VOTING POWER", "SHARE", "PRIORITY")
+	for _, v := range validators {
+		power := v.Get("voting_power").Int()
+		share := 0.0
+		// don't divide by 0
+		if totalPower > 0 {
+			share = float64(power) / float64(totalPower) * 100
+		}
+		fmt.Fprintf(&b, "%-42s %18s %7.2f%% %18s\n",
+			v.Get("address").String(),
+			numberWithComma(power),
+			share,
+			numberWithComma(v.Get("proposer_priority").Int()),
+		)
+	}
+	fmt.Fprintf(&b, "\n%d validators", len(validators))
+
+	return b.String()
+}
+
+// PEERS PAGE
+
+// peersPage creates the page listing the connected peers.
+func peersPage(ctx context.Context, delay time.Duration) page {
+	peerListWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := peerListWidget.Write("⌛ loading"); err != nil {
+		panic(err)
+	}
+
+	go writePeerList(ctx, peerListWidget, delay)
+
+	return page{
+		name: "Peers",
+		layout: []container.Option{
+			container.Border(linestyle.Light),
+			container.BorderTitle("Connected Peers"),
+			container.PlaceWidget(peerListWidget),
+		},
+	}
+}
+
+// writePeerList writes the connected peers with their connection details.
+// Exits when the context expires.
+func writePeerList(ctx context.Context, t *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			netInfoRPC, err := getFromRPC("net_info")
+			if err != nil {
+				continue
+			}
+			peers := gjson.Get(netInfoRPC, "result.peers")
+			if !peers.Exists() {
+				continue
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "%-20s %-14s %-16s %-4s %-12s %12s %12s\n", "MONIKER", "ID", "REMOTE IP", "DIR", "VERSION", "SEND", "RECV")
+			for _, peer := range peers.Array() {
+				direction := "in"
+				if peer.Get("is_outbound").Bool() {
+					direction = "out"
+				}
+				fmt.Fprintf(&b, "%-20s %-14s %-16s %-4s %-12s %10s/s %10s/s\n",
+					shorten(peer.Get("node_info.moniker").String(), 20),
+					shorten(peer.Get("node_info.id").String(), 12),
+					peer.Get("remote_ip").String(),
+					direction,
+					peer.Get("node_info.version").String(),
+					byteCountDecimal(peer.Get("connection_status.SendMonitor.AvgRate").Int()),
+					byteCountDecimal(peer.Get("connection_status.RecvMonitor.AvgRate").Int()),
+				)
+			}
+
+			t.Reset()
+			if err := t.Write(b.String()); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// CONSENSUS PAGE
+
+// consensusPage creates the page showing the consensus round state next to
+// the block round donut.
+func consensusPage(ctx context.Context, d *donut.Donut, delay time.Duration) page {
+	roundStateWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := roundStateWidget.Write("⌛ loading"); err != nil {
+		panic(err)
+	}
+
+	go writeRoundState(ctx, roundStateWidget, delay)
+
+	return page{
+		name: "Consensus",
+		layout: []container.Option{
+			container.SplitVertical(
+				container.Left(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Round State"),
+					container.PlaceWidget(roundStateWidget),
+				),
+				container.Right(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Current Block Round"),
+					container.PlaceWidget(d),
+				),
+				container.SplitPercent(60),
+			),
+		},
+	}
+}
+
+// roundSteps names the consensus steps as numbered by CometBFT.
+var roundSteps = map[string]string{
+	"1": "NewHeight",
+	"2": "NewRound",
+	"3": "Propose",
+	"4": "Prevote",
+	"5": "PrevoteWait",
+	"6": "Precommit",
+	"7": "PrecommitWait",
+	"8": "Commit",
+}
+
+// writeRoundState writes the consensus round state with its votes.
+// Exits when the context expires.
+func writeRoundState(ctx context.Context, t *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			consensusRPC, err := getFromRPC("consensus_state")
+			if err != nil {
+				continue
+			}
+			roundState := gjson.Get(consensusRPC, "result.round_state")
+			if !roundState.Exists() {
+				continue
+			}
+
+			hrs := strings.Split(roundState.Get("height/round/step").String(), "/")
+			if len(hrs) != 3 {
+				continue
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "Height     %s\n", hrs[0])
+			fmt.Fprintf(&b, "Round      %s\n", hrs[1])
+			fmt.Fprintf(&b, "Step       %s\n", roundSteps[hrs[2]])
+			fmt.Fprintf(&b, "Started    %s\n", roundState.Get("start_time").String())
+			fmt.Fprintf(&b, "Proposal   %s\n\n", roundState.Get("proposal_block_hash").String())
+			for _, votes := range roundState.Get("height_vote_set").Array() {
+				fmt.Fprintf(&b, "Round %s\n", votes.Get("round").String())
+				fmt.Fprintf(&b, "  prevotes   %s\n", votes.Get("prevotes_bit_array").String())
+				fmt.Fprintf(&b, "  precommits %s\n", votes.Get("precommits_bit_array").String())
+			}
+
+			t.Reset()
+			if err := t.Write(b.String()); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// MODULES PAGE
+
+// modulesPage creates the page describing the application and node software
+// and the versions of the application modules.
+func modulesPage(ctx context.Context, delay time.Duration) page {
+	applicationWidget, err := text.New(text.WrapAtRunes())
+	if err != nil {
+		panic(err)
+	}
+	if err := applicationWidget.Write("⌛ loading"); err != nil {
+		panic(err)
+	}
+	moduleVersionsWidget, err := text.New()
+	if err != nil {
+		panic(err)
+	}
+	if err := moduleVersionsWidget.Write("⌛ loading"); err != nil {
+		panic(err)
+	}
+
+	go writeApplication(ctx, applicationWidget, delay)
+	go writeModuleVersions(ctx, moduleVersionsWidget, delay)
+
+	return page{
+		name: "Modules",
+		layout: []container.Option{
+			container.SplitVertical(
+				container.Left(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Application"),
+					container.PlaceWidget(applicationWidget),
+				),
+				container.Right(
+					container.Border(linestyle.Light),
+					container.BorderTitle("Module Versions"),
+					container.PlaceWidget(moduleVersionsWidget),
+				),
+				container.SplitPercent(60),
+			),
+		},
+	}
+}
+
+// writeModuleVersions writes the consensus versions of the application
+// modules reported by the upgrade module.
+// Exits when the context expires.
+func writeModuleVersions(ctx context.Context, t *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			versions, err := getFromAPI("/cosmos/upgrade/v1beta1/module_versions")
+			t.Reset()
+			if err != nil {
+				if err := t.Write(err.Error()); err != nil {
+					panic(err)
+				}
+				continue
+			}
+
+			var b strings.Builder
+			for _, module := range gjson.Get(versions, "module_versions").Array() {
+				fmt.Fprintf(&b, "%-20s %s\n", module.Get("name").String(), module.Get("version").String())
+			}
+			if b.Len() == 0 {
+				b.WriteString("No module versions reported")
+			}
+			if err := t.Write(b.String()); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// writeApplication writes the ABCI application and node versions.
+// Exits when the context expires.
+func writeApplication(ctx context.Context, t *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			abciInfoRPC, err := getFromRPC("abci_info")
+			if err != nil {
+				continue
+			}
+			statusRPC, err := getFromRPC("status")
+			if err != nil {
+				continue
+			}
+			app := gjson.Get(abciInfoRPC, "result.response")
+			node := gjson.Get(statusRPC, "result.node_info")
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "Application     %s\n", app.Get("data").String())
+			fmt.Fprintf(&b, "Version         %s\n", app.Get("version").String())
+			fmt.Fprintf(&b, "App Version     %s\n", app.Get("app_version").String())
+			fmt.Fprintf(&b, "Last Height     %s\n", numberWithComma(app.Get("last_block_height").Int()))
+			fmt.Fprintf(&b, "Last App Hash   %s\n\n", app.Get("last_block_app_hash").String())
+			fmt.Fprintf(&b, "Node Moniker    %s\n", node.Get("moniker").String())
+			fmt.Fprintf(&b, "Node Version    %s\n", node.Get("version").String())
+			fmt.Fprintf(&b, "Protocol        p2p %s, block %s, app %s\n",
+				node.Get("protocol_version.p2p").String(),
+				node.Get("protocol_version.block").String(),
+				node.Get("protocol_version.app").String(),
+			)
+			fmt.Fprintf(&b, "Tx Index        %s\n", node.Get("other.tx_index").String())
+
+			t.Reset()
+			if err := t.Write(b.String()); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// shorten cuts s to at most n characters.
+func shorten(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n])
+}
+
+// formatTime formats a RFC 3339 timestamp in the local time zone.
+func formatTime(s string) string {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return s
+	}
+
+	return t.Local().Format("2006-01-02 03:04:05 PM")
+}
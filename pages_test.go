@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mum4k/termdash/keyboard"
+)
+
+func TestPageKey(t *testing.T) {
+	tests := []struct {
+		page int
+		key  keyboard.Key
+		ok   bool
+	}{
+		{0, '1', true},
+		{8, '9', true},
+		{9, '0', true},
+		{10, 0, false},
+		{14, 0, false},
+	}
+
+	for _, tt := range tests {
+		key, ok := pageKey(tt.page)
+		if key != tt.key || ok != tt.ok {
+			t.Errorf("pageKey(%d) = %q, %v, want %q, %v", tt.page, key, ok, tt.key, tt.ok)
+		}
+	}
+}
+
+func TestTabWindow(t *testing.T) {
+	widths := []int{10, 10, 10, 10, 10}
+
+	tests := []struct {
+		name        string
+		active      int
+		width       int
+		first, last int
+	}{
+		{"all tabs fit", 2, 50, 0, 4},
+		{"first tab active", 0, 32, 0, 2},
+		{"middle tab active", 3, 32, 1, 3},
+		{"last tab active", 4, 32, 2, 4},
+		{"markers need room", 0, 30, 0, 1},
+		{"narrower than a tab", 3, 5, 3, 3},
+	}
+
+	for _, tt := range tests {
+		first, last := tabWindow(widths, tt.active, tt.width)
+		if first != tt.first || last != tt.last {
+			t.Errorf("%s: tabWindow() = %d, %d, want %d, %d", tt.name, first, last, tt.first, tt.last)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// protobuf wire types used by the Cosmos SDK transactions
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protoField is a single field of an encoded protobuf message.
+type protoField struct {
+	number   int
+	wireType int
+	varint   uint64
+	bytes    []byte
+}
+
+// protoFields splits an encoded protobuf message into its fields, it's enough
+// to read transactions without their generated types.
+func protoFields(b []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(b) > 0 {
+		key, n, err := protoVarint(b)
+		if err != nil {
+			return nil, err
+		}
+		b = b[n:]
+
+		f := protoField{number: int(key >> 3), wireType: int(key & 7)}
+		switch f.wireType {
+		case wireVarint:
+			f.varint, n, err = protoVarint(b)
+			if err != nil {
+				return nil, err
+			}
+		case wireFixed64:
+			n = 8
+		case wireFixed32:
+			n = 4
+		case wireBytes:
+			length, m, err := protoVarint(b)
+			if err != nil {
+				return nil, err
+			}
+			if uint64(len(b)-m) < length {
+				return nil, errors.New("protobuf field exceeds the message")
+			}
+			f.bytes = b[m : m+int(length)]
+			n = m + int(length)
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", f.wireType)
+		}
+		if len(b) < n {
+			return nil, errors.New("truncated protobuf message")
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// protoVarint reads a varint and returns it with the number of bytes read.
+func protoVarint(b []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i] < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+
+	return 0, 0, errors.New("invalid protobuf varint")
+}
+
+// protoAppendVarint appends a varint field, zero values are omitted like
+// proto3 encoders do.
+func protoAppendVarint(b []byte, number int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendVarint(b, uint64(number)<<3|wireVarint)
+
+	return appendVarint(b, v)
+}
+
+// protoAppendFixed64 appends a fixed64 field, zero values are omitted.
+func protoAppendFixed64(b []byte, number int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendVarint(b, uint64(number)<<3|wireFixed64)
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+
+	return b
+}
+
+// protoAppendBytes appends a bytes or string field, empty values are omitted.
+func protoAppendBytes(b []byte, number int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+
+	return protoAppendMessage(b, number, v)
+}
+
+// protoAppendMessage appends an embedded message, even an empty one.
+func protoAppendMessage(b []byte, number int, v []byte) []byte {
+	b = appendVarint(b, uint64(number)<<3|wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+
+	return append(b, v...)
+}
+
+// appendVarint appends a varint.
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+
+	return append(b, byte(v))
+}
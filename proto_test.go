@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProtoVarint(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		value uint64
+		n     int
+		valid bool
+	}{
+		{"zero", []byte{0x00}, 0, 1, true},
+		{"one byte", []byte{0x7f}, 127, 1, true},
+		{"two bytes", []byte{0xac, 0x02}, 300, 2, true},
+		{"trailing bytes", []byte{0x96, 0x01, 0xff}, 150, 2, true},
+		{"max uint64", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, 1<<64 - 1, 10, true},
+		{"empty", nil, 0, 0, false},
+		{"truncated", []byte{0x80}, 0, 0, false},
+		{"too long", bytes.Repeat([]byte{0x80}, 11), 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, n, err := protoVarint(tt.input)
+			if (err == nil) != tt.valid {
+				t.Fatalf("protoVarint(%x) error = %v, want valid %v", tt.input, err, tt.valid)
+			}
+			if value != tt.value || n != tt.n {
+				t.Errorf("protoVarint(%x) = %d, %d, want %d, %d", tt.input, value, n, tt.value, tt.n)
+			}
+		})
+	}
+}
+
+func TestProtoFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  []byte
+		fields []protoField
+		valid  bool
+	}{
+		{"empty", nil, nil, true},
+		{"varint", []byte{0x08, 0x96, 0x01}, []protoField{{number: 1, wireType: wireVarint, varint: 150}}, true},
+		{"bytes", []byte{0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g'}, []protoField{{number: 2, wireType: wireBytes, bytes: []byte("testing")}}, true},
+		{"fixed", []byte{0x19, 1, 2, 3, 4, 5, 6, 7, 8, 0x25, 1, 2, 3, 4}, []protoField{{number: 3, wireType: wireFixed64}, {number: 4, wireType: wireFixed32}}, true},
+		{"several", []byte{0x08, 0x01, 0x12, 0x00, 0x08, 0x02}, []protoField{
+			{number: 1, wireType: wireVarint, varint: 1},
+			{number: 2, wireType: wireBytes, bytes: []byte{}},
+			{number: 1, wireType: wireVarint, varint: 2},
+		}, true},
+		{"bytes exceed the message", []byte{0x12, 0x08, 't', 'e', 's', 't'}, nil, false},
+		{"truncated fixed64", []byte{0x19, 1, 2, 3}, nil, false},
+		{"truncated varint", []byte{0x08, 0x80}, nil, false},
+		{"group wire type", []byte{0x0b}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := protoFields(tt.input)
+			if (err == nil) != tt.valid {
+				t.Fatalf("protoFields(%x) error = %v, want valid %v", tt.input, err, tt.valid)
+			}
+			if len(fields) != len(tt.fields) {
+				t.Fatalf("protoFields(%x) = %d fields, want %d", tt.input, len(fields), len(tt.fields))
+			}
+			for i, f := range fields {
+				want := tt.fields[i]
+				if f.number != want.number || f.wireType != want.wireType || f.varint != want.varint || !bytes.Equal(f.bytes, want.bytes) {
+					t.Errorf("protoFields(%x)[%d] = %+v, want %+v", tt.input, i, f, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProtoAppend(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{"varint", protoAppendVarint(nil, 1, 150), []byte{0x08, 0x96, 0x01}},
+		{"zero varint", protoAppendVarint(nil, 1, 0), nil},
+		{"fixed64", protoAppendFixed64(nil, 2, 1), []byte{0x11, 1, 0, 0, 0, 0, 0, 0, 0}},
+		{"zero fixed64", protoAppendFixed64(nil, 2, 0), nil},
+		{"bytes", protoAppendBytes(nil, 2, []byte("testing")), []byte{0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g'}},
+		{"empty bytes", protoAppendBytes(nil, 2, nil), nil},
+		{"empty message", protoAppendMessage(nil, 3, nil), []byte{0x1a, 0x00}},
+		{"appended", protoAppendVarint([]byte{0x08, 0x01}, 16, 1), []byte{0x08, 0x01, 0x80, 0x01, 0x01}},
+	}
+
+	for _, tt := range tests {
+		if !bytes.Equal(tt.got, tt.want) {
+			t.Errorf("%s = %x, want %x", tt.name, tt.got, tt.want)
+		}
+		if len(tt.want) == 0 {
+			continue
+		}
+		if _, err := protoFields(tt.got); err != nil {
+			t.Errorf("%s doesn't decode: %v", tt.name, err)
+		}
+	}
+}
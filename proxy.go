@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// proxyURL is the proxy given with -proxy, all traffic goes through it.
+var proxyURL *url.URL
+
+// envProxy picks the proxy of a URL from HTTPS_PROXY, HTTP_PROXY and
+// NO_PROXY, ALL_PROXY is used for the schemes without a proxy.
+var envProxy func(target *url.URL) (*url.URL, error)
+
+// setupProxy checks the -proxy URL and reads the proxies of the environment.
+func setupProxy() error {
+	config := httpproxy.FromEnvironment()
+	all := os.Getenv("ALL_PROXY")
+	if all == "" {
+		all = os.Getenv("all_proxy")
+	}
+	if config.HTTPSProxy == "" {
+		config.HTTPSProxy = all
+	}
+	if config.HTTPProxy == "" {
+		config.HTTPProxy = all
+	}
+	envProxy = config.ProxyFunc()
+
+	if *givenProxy == "" {
+		return nil
+	}
+	u, err := url.Parse(*givenProxy)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid proxy URL %q, expected socks5://[user:pass@]host:port or http://host:port", *givenProxy)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q, expected socks5 or http", u.Scheme)
+	}
+	proxyURL = u
+
+	return nil
+}
+
+// proxyFor returns the proxy of a URL or nil to connect directly.
+func proxyFor(target *url.URL) (*url.URL, error) {
+	if proxyURL != nil {
+		return proxyURL, nil
+	}
+	if envProxy == nil {
+		return nil, nil
+	}
+
+	return envProxy(target)
+}
+
+// newTransport creates the HTTP transport of the RPC with the TLS config and
+// the proxy of the flags.
+func newTransport() *http.Transport {
+	transport := newProxyTransport()
+	transport.TLSClientConfig = clientTLS
+
+	return transport
+}
+
+// newProxyTransport creates an HTTP transport with the proxy of the flags.
+func newProxyTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			u, err := proxyFor(req.URL)
+			// net/http resolves the host on the proxy either way
+			if u != nil && u.Scheme == "socks5h" {
+				u = &url.URL{Scheme: "socks5", User: u.User, Host: u.Host}
+			}
+			return u, err
+		},
+	}
+}
+
+// proxyDialer returns the dialer of the connections to a URL, through its
+// proxy if it has one.
+func proxyDialer(target *url.URL) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		u, err := proxyFor(target)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}
+
+		if u.Scheme == "socks5" || u.Scheme == "socks5h" {
+			var auth *proxy.Auth
+			if u.User != nil {
+				password, _ := u.User.Password()
+				auth = &proxy.Auth{User: u.User.Username(), Password: password}
+			}
+			dialer, err := proxy.SOCKS5("tcp", u.Host, auth, proxy.Direct)
+			if err != nil {
+				return nil, err
+			}
+			return dialer.(proxy.ContextDialer).DialContext(ctx, network, addr)
+		}
+
+		return dialConnect(ctx, u, addr)
+	}
+}
+
+// dialConnect opens a tunnel to addr with the CONNECT method of an HTTP proxy.
+func dialConnect(ctx context.Context, u *url.URL, addr string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+	// the context bounds the handshake with the proxy too
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %s", u.Host, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listen serves the connections of a local listener with handle until the
+// test ends.
+func listen(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// tunnel pipes a client connection to addr.
+func tunnel(client net.Conn, addr string) {
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		return
+	}
+	defer target.Close()
+
+	go io.Copy(target, client)
+	io.Copy(client, target)
+}
+
+// connectProxy serves the CONNECT method, with basic auth if credentials
+// aren't empty.
+func connectProxy(credentials string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil || req.Method != http.MethodConnect {
+			return
+		}
+		if credentials != "" && req.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)) {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		tunnel(conn, req.Host)
+	}
+}
+
+// socks5Proxy serves the CONNECT command of SOCKS5 with the username and
+// password authentication.
+func socks5Proxy(user, password string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(r, make([]byte, header[1])); err != nil {
+			return
+		}
+		conn.Write([]byte{5, 2})
+
+		// RFC 1929
+		version, _ := r.ReadByte()
+		length, _ := r.ReadByte()
+		gotUser := make([]byte, length)
+		io.ReadFull(r, gotUser)
+		length, _ = r.ReadByte()
+		gotPassword := make([]byte, length)
+		io.ReadFull(r, gotPassword)
+		if version != 1 || string(gotUser) != user || string(gotPassword) != password {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+
+		request := make([]byte, 4)
+		if _, err := io.ReadFull(r, request); err != nil {
+			return
+		}
+		var host string
+		switch request[3] {
+		case 1:
+			ip := make([]byte, 4)
+			io.ReadFull(r, ip)
+			host = net.IP(ip).String()
+		case 3:
+			length, _ := r.ReadByte()
+			name := make([]byte, length)
+			io.ReadFull(r, name)
+			host = string(name)
+		default:
+			return
+		}
+		port := make([]byte, 2)
+		io.ReadFull(r, port)
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+		tunnel(conn, net.JoinHostPort(host, fmt.Sprint(binary.BigEndian.Uint16(port))))
+	}
+}
+
+func TestProxyDialer(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"result":{}}`)
+	}))
+	defer node.Close()
+
+	tests := []struct {
+		name  string
+		proxy string
+		valid bool
+	}{
+		{"connect", "http://" + listen(t, connectProxy("")), true},
+		{"connect with auth", "http://u:p@" + listen(t, connectProxy("u:p")), true},
+		{"connect auth rejected", "http://u:wrong@" + listen(t, connectProxy("u:p")), false},
+		{"socks5", "socks5://u:p@" + listen(t, socks5Proxy("u", "p")), true},
+		{"socks5 auth rejected", "socks5://u:wrong@" + listen(t, socks5Proxy("u", "p")), false},
+	}
+	defer func() { proxyURL = nil }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyURL, _ = url.Parse(tt.proxy)
+			target, _ := url.Parse(node.URL)
+			client := &http.Client{Transport: &http.Transport{DialContext: proxyDialer(target)}, Timeout: 5 * time.Second}
+
+			resp, err := client.Get(node.URL + "/status")
+			if (err == nil) != tt.valid {
+				t.Fatalf("GET through %s error = %v, want valid %v", tt.proxy, err, tt.valid)
+			}
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if !strings.Contains(string(body), "result") {
+				t.Errorf("GET through %s = %q", tt.proxy, body)
+			}
+		})
+	}
+}
+
+func TestDialConnectDeadline(t *testing.T) {
+	// the proxy accepts the connection but never answers the CONNECT
+	silent := listen(t, func(conn net.Conn) {
+		io.Copy(io.Discard, conn)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := dialConnect(ctx, &url.URL{Scheme: "http", Host: silent}, "localhost:26657"); err == nil {
+		t.Fatal("dialConnect to a silent proxy didn't fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("dialConnect took %s, longer than the deadline of the context", elapsed)
+	}
+
+	// the deadline is cleared once the tunnel is open
+	open := listen(t, connectProxy(""))
+	echo := listen(t, func(conn net.Conn) {
+		io.Copy(conn, conn)
+	})
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	conn, err := dialConnect(ctx, &url.URL{Scheme: "http", Host: open}, echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	time.Sleep(200 * time.Millisecond)
+	if _, err := io.WriteString(conn, "ping"); err != nil {
+		t.Fatalf("write after the deadline of the context: %v", err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != "ping" {
+		t.Errorf("read after the deadline of the context = %q, %v", reply, err)
+	}
+}
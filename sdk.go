@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"gopkg.in/resty.v1"
+)
+
+// errNoAPI is returned when a Cosmos SDK query is made without the -a flag.
+var errNoAPI = errors.New("no Cosmos SDK REST API configured, start gex with -a <url>")
+
+// getFromAPI gets data from the Cosmos SDK REST API and turns an error
+// response of the gRPC gateway into an error.
+func getFromAPI(path string) (string, error) {
+	if *givenAPI == "" {
+		return "", errNoAPI
+	}
+
+	resp, err := resty.R().
+		SetHeader("Cache-Control", "no-cache").
+		SetHeader("Content-Type", "application/json").
+		Get(strings.TrimSuffix(*givenAPI, "/") + path)
+	if err != nil {
+		return "", err
+	}
+	if resp.IsError() {
+		if message := gjson.Get(resp.String(), "message"); message.Exists() {
+			return "", fmt.Errorf("%s: %s", path, message.String())
+		}
+		return "", fmt.Errorf("%s: %s", path, resp.Status())
+	}
+
+	return resp.String(), nil
+}
+
+// formatCoins formats a list of coins as returned by the REST API.
+func formatCoins(coins gjson.Result) string {
+	var formatted []string
+	for _, coin := range coins.Array() {
+		formatted = append(formatted, formatCoin(coin.Get("amount").String(), coin.Get("denom").String()))
+	}
+	if len(formatted) == 0 {
+		return "-"
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// formatAmount adds commas to the integer part of a decimal amount and drops
+// trailing zeros of the fractional part.
+func formatAmount(amount string) string {
+	integer, fraction := amount, ""
+	if i := strings.Index(amount, "."); i >= 0 {
+		integer, fraction = amount[:i], strings.TrimRight(amount[i+1:], "0")
+	}
+
+	var b strings.Builder
+	if n, err := strconv.ParseInt(integer, 10, 64); err == nil {
+		b.WriteString(numberWithComma(n))
+	} else {
+		// amounts beyond int64, e.g. of 18 decimal denoms
+		for i, digit := range integer {
+			if i > 0 && (len(integer)-i)%3 == 0 {
+				b.WriteRune(',')
+			}
+			b.WriteRune(digit)
+		}
+	}
+	if fraction != "" {
+		b.WriteString("." + fraction)
+	}
+
+	return b.String()
+}
+
+// addAmounts adds two integer amounts of arbitrary size, invalid amounts
+// count as 0.
+func addAmounts(a, b string) string {
+	x, ok := new(big.Int).SetString(a, 10)
+	if !ok {
+		x = new(big.Int)
+	}
+	y, ok := new(big.Int).SetString(b, 10)
+	if !ok {
+		y = new(big.Int)
+	}
+
+	return x.Add(x, y).String()
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	heightPattern = regexp.MustCompile(`^[0-9]+$`)
+	hashPattern   = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{64}$`)
+	// bech32 human readable part, separator and at least the 6 checksum characters
+	addressPattern = regexp.MustCompile(`^[a-z][a-z0-9]*1[qpzry9x8gf2tvdw0s3jn54khce6mua7l]{6,}$`)
+)
+
+// search looks up blocks, transactions and addresses and writes the result to
+// the detail view of the Search page.
+type search struct {
+	t  *text.Text
+	mu sync.Mutex
+	// generation drops the results of lookups a newer search replaced
+	generation int
+}
+
+// searchPage creates the page showing the search results.
+func searchPage() (page, *search) {
+	searchResultWidget, err := text.New(text.WrapAtRunes())
+	if err != nil {
+		panic(err)
+	}
+	if err := searchResultWidget.Write("Press / and enter a block height, block hash, tx hash or address.\n"); err != nil {
+		panic(err)
+	}
+
+	return page{
+		name: "Search",
+		layout: []container.Option{
+			container.Border(linestyle.Light),
+			container.BorderTitle("Search Result"),
+			container.PlaceWidget(searchResultWidget),
+		},
+	}, &search{t: searchResultWidget}
+}
+
+// lookup runs the query in the background and writes its result once known.
+func (s *search) lookup(query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	generation := s.generation
+	s.t.Reset()
+	if err := s.t.Write(fmt.Sprintf("⌛ searching %s\n", query)); err != nil {
+		panic(err)
+	}
+
+	go func() {
+		result, err := lookup(query)
+		if err != nil {
+			result = fmt.Sprintf("✖️ %s\n", err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// a newer search is running or done
+		if generation != s.generation {
+			return
+		}
+		s.t.Reset()
+		if err := s.t.Write(result); err != nil {
+			panic(err)
+		}
+	}()
+}
+
+// lookup routes the query to the RPC call matching its format.
+func lookup(query string) (string, error) {
+	switch {
+	case heightPattern.MatchString(query):
+		return lookupBlock("block?height=" + query)
+	case hashPattern.MatchString(query):
+		hash := "0x" + strings.TrimPrefix(query, "0x")
+		if tx, err := lookupTx(hash); err == nil {
+			return tx, nil
+		}
+		return lookupBlock("block_by_hash?hash=" + hash)
+	case addressPattern.MatchString(query):
+		return lookupAddress(query)
+	}
+
+	return "", fmt.Errorf("%q is neither a block height, a hash nor an address", query)
+}
+
+// lookupBlock describes the block returned by the endpoint together with the
+// results of its transactions.
+func lookupBlock(endpoint string) (string, error) {
+	blockRPC, err := getResultFromRPC(endpoint)
+	if err != nil {
+		return "", err
+	}
+	block := gjson.Get(blockRPC, "result.block")
+	if !block.Exists() || block.Type == gjson.Null {
+		return "", errors.New("block not found")
+	}
+
+	height := block.Get("header.height").String()
+	blockResultsRPC, _ := getFromRPC("block_results?height=" + height)
+	txResults := gjson.Get(blockResultsRPC, "result.txs_results").Array()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "BLOCK %s\n\n", numberWithComma(block.Get("header.height").Int()))
+	fmt.Fprintf(&b, "Hash            %s\n", gjson.Get(blockRPC, "result.block_id.hash").String())
+	fmt.Fprintf(&b, "Chain           %s\n", block.Get("header.chain_id").String())
+	fmt.Fprintf(&b, "Time            %s\n", formatTime(block.Get("header.time").String()))
+	fmt.Fprintf(&b, "Proposer        %s\n", block.Get("header.proposer_address").String())
+	fmt.Fprintf(&b, "App Hash        %s\n", block.Get("header.app_hash").String())
+	fmt.Fprintf(&b, "Signatures      %d\n", len(block.Get("last_commit.signatures").Array()))
+
+	txs := block.Get("data.txs").Array()
+	fmt.Fprintf(&b, "Transactions    %d\n\n", len(txs))
+	for i, tx := range txs {
+		code, gas := "", ""
+		if i < len(txResults) {
+			code = fmt.Sprintf("code %d", txResults[i].Get("code").Int())
+			gas = fmt.Sprintf("%s / %s gas",
+				numberWithComma(txResults[i].Get("gas_used").Int()),
+				numberWithComma(txResults[i].Get("gas_wanted").Int()),
+			)
+		}
+		fmt.Fprintf(&b, "  %s  %-8s %s\n", txHash(tx.String()), code, gas)
+	}
+
+	return b.String(), nil
+}
+
+// lookupTx describes the transaction with the hash.
+func lookupTx(hash string) (string, error) {
+	txRPC, err := getResultFromRPC("tx?hash=" + hash)
+	if err != nil {
+		return "", err
+	}
+	tx := gjson.Get(txRPC, "result")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "TRANSACTION %s\n\n", tx.Get("hash").String())
+	fmt.Fprintf(&b, "Height          %s\n", numberWithComma(tx.Get("height").Int()))
+	fmt.Fprintf(&b, "Index           %d\n", tx.Get("index").Int())
+	fmt.Fprintf(&b, "Code            %d %s\n", tx.Get("tx_result.code").Int(), tx.Get("tx_result.codespace").String())
+	fmt.Fprintf(&b, "Gas             %s / %s\n",
+		numberWithComma(tx.Get("tx_result.gas_used").Int()),
+		numberWithComma(tx.Get("tx_result.gas_wanted").Int()),
+	)
+	fmt.Fprintf(&b, "Log             %s\n\n", tx.Get("tx_result.log").String())
+	b.WriteString("Events\n")
+	for _, event := range tx.Get("tx_result.events").Array() {
+		fmt.Fprintf(&b, "  %s\n", event.Get("type").String())
+		for _, attr := range event.Get("attributes").Array() {
+			fmt.Fprintf(&b, "    %s = %s\n", eventAttribute(attr.Get("key").String()), humanizeCoins(eventAttribute(attr.Get("value").String())))
+		}
+	}
+
+	return b.String(), nil
+}
+
+// lookupAddress lists the latest transactions sent or received by the address.
+func lookupAddress(address string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ADDRESS %s\n", address)
+
+	for _, query := range []string{"message.sender", "transfer.recipient"} {
+		params := url.Values{}
+		params.Set("query", fmt.Sprintf("\"%s='%s'\"", query, address))
+		params.Set("per_page", "30")
+		params.Set("order_by", "\"desc\"")
+
+		txSearchRPC, err := getResultFromRPC("tx_search?" + params.Encode())
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&b, "\n%s (%s total)\n", query, gjson.Get(txSearchRPC, "result.total_count").String())
+		for _, tx := range gjson.Get(txSearchRPC, "result.txs").Array() {
+			fmt.Fprintf(&b, "  #%-10s %s  code %d\n",
+				numberWithComma(tx.Get("height").Int()),
+				tx.Get("hash").String(),
+				tx.Get("tx_result.code").Int(),
+			)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// getResultFromRPC gets data from the RPC endpoint and turns a JSON-RPC error
+// response into an error.
+func getResultFromRPC(endpoint string) (string, error) {
+	resp, err := getFromRPC(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if rpcErr := gjson.Get(resp, "error"); rpcErr.Exists() {
+		return "", fmt.Errorf("%s %s", rpcErr.Get("message").String(), rpcErr.Get("data").String())
+	}
+
+	return resp, nil
+}
+
+// txHash calculates the hash of a base64 encoded transaction.
+func txHash(tx string) string {
+	raw, err := base64.StdEncoding.DecodeString(tx)
+	if err != nil {
+		return "?"
+	}
+	sum := sha256.Sum256(raw)
+
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// eventAttribute decodes event attributes which older CometBFT versions
+// return base64 encoded.
+func eventAttribute(s string) string {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(raw) == 0 || !utf8.Valid(raw) {
+		return s
+	}
+	for _, r := range string(raw) {
+		if !unicode.IsPrint(r) {
+			return s
+		}
+	}
+
+	return string(raw)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// signatureWindow is the number of recent blocks the missed blocks are
+// counted in.
+const signatureWindow = 100
+
+// blockIDFlagAbsent marks a validator that didn't sign a commit.
+const blockIDFlagAbsent = 1
+
+// Signatures tracks the recent blocks and the validators that missed signing
+// them
+type Signatures struct {
+	mu     sync.Mutex
+	blocks []blockSummary
+	// validator set that signed the commit of the latest block
+	set     []lightValidator
+	setHash string
+	// validators hash and height of the latest block
+	lastHash   string
+	lastHeight int64
+}
+
+// blockSummary is a block as seen by gex, with the commit of its previous
+// block
+type blockSummary struct {
+	height   int64
+	time     time.Time
+	interval time.Duration
+	proposer string
+	txs      int
+	signed   int
+	// addresses of the validators that didn't sign the previous block, only
+	// known when the validator set of the previous block is
+	absent     []string
+	attributed bool
+}
+
+// validatorSigning is a validator of the latest set with the blocks it missed.
+type validatorSigning struct {
+	address string
+	power   int64
+	missed  int
+}
+
+// count counts the signatures of the commit in a NewBlock event. Queries the
+// validator set when it changed.
+func (s *Signatures) count(block gjson.Result) {
+	header := block.Get("header")
+	height := header.Get("height").Int()
+	blockTime, _ := time.Parse(time.RFC3339Nano, header.Get("time").String())
+
+	s.mu.Lock()
+	set, setHash := s.set, s.setHash
+	known := set != nil && s.lastHeight == height-1 && s.lastHash == setHash
+	s.mu.Unlock()
+
+	// the commit of the previous block is signed by the validator set of it
+	if !known && height > 1 {
+		set, setHash = nil, ""
+		if validators, err := queryValidators(height - 1); err == nil {
+			set, setHash = validators, strings.ToUpper(hex.EncodeToString(validatorSetHash(validators)))
+		}
+	}
+
+	summary := blockSummary{
+		height:   height,
+		time:     blockTime,
+		proposer: header.Get("proposer_address").String(),
+		txs:      len(block.Get("data.txs").Array()),
+	}
+	signatures := block.Get("last_commit.signatures").Array()
+	// the signatures are in the order of the validator set
+	summary.attributed = len(set) == len(signatures)
+	for i, signature := range signatures {
+		if signature.Get("block_id_flag").Int() != blockIDFlagAbsent {
+			summary.signed++
+			continue
+		}
+		if summary.attributed {
+			summary.absent = append(summary.absent, strings.ToUpper(hex.EncodeToString(set[i].address)))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n := len(s.blocks); n > 0 && s.blocks[n-1].height == height-1 {
+		summary.interval = blockTime.Sub(s.blocks[n-1].time)
+	}
+	s.blocks = append(s.blocks, summary)
+	if len(s.blocks) > signatureWindow {
+		s.blocks = s.blocks[1:]
+	}
+	s.set, s.setHash = set, setHash
+	s.lastHash, s.lastHeight = strings.ToUpper(header.Get("validators_hash").String()), height
+}
+
+// recent returns the recent blocks, the latest first.
+func (s *Signatures) recent() []blockSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blocks := make([]blockSummary, len(s.blocks))
+	for i, b := range s.blocks {
+		blocks[len(s.blocks)-1-i] = b
+	}
+
+	return blocks
+}
+
+// validators returns the validators of the latest set with the blocks they
+// missed, the most missed first, and the number of blocks they are counted in.
+func (s *Signatures) validators() ([]validatorSigning, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	missed := map[string]int{}
+	blocks := 0
+	for _, b := range s.blocks {
+		if !b.attributed {
+			continue
+		}
+		blocks++
+		for _, address := range b.absent {
+			missed[address]++
+		}
+	}
+
+	var validators []validatorSigning
+	for _, v := range s.set {
+		address := strings.ToUpper(hex.EncodeToString(v.address))
+		validators = append(validators, validatorSigning{address: address, power: v.power, missed: missed[address]})
+	}
+	sort.SliceStable(validators, func(i, j int) bool {
+		return validators[i].missed > validators[j].missed
+	})
+
+	return validators, blocks
+}
+
+// averageInterval returns the average time between the recent blocks.
+func (s *Signatures) averageInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total time.Duration
+	n := 0
+	for _, b := range s.blocks {
+		if b.interval > 0 {
+			total += b.interval
+			n++
+		}
+	}
+	// don't divide by 0
+	if n == 0 {
+		return 0
+	}
+
+	return total / time.Duration(n)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// selfDelegationRefresh is how often the self-delegations are queried, they
+// take one query per validator.
+const selfDelegationRefresh = 1 * time.Minute
+
+// bondStatuses names the x/staking bond statuses.
+var bondStatuses = map[string]string{
+	"BOND_STATUS_BONDED":    "bonded",
+	"BOND_STATUS_UNBONDING": "unbonding",
+	"BOND_STATUS_UNBONDED":  "unbonded",
+}
+
+// stakingValidator is a x/staking validator joined with its voting power in
+// the CometBFT validator set.
+type stakingValidator struct {
+	moniker     string
+	operator    string
+	status      string
+	tokens      *big.Int
+	commission  float64
+	votingPower int64
+}
+
+// selfDelegations caches the amount each operator delegated to itself.
+type selfDelegations struct {
+	mu         sync.Mutex
+	amounts    map[string]string
+	refreshed  time.Time
+	refreshing bool
+}
+
+// get returns the cached amounts and queries them again in the background
+// when they are stale.
+func (s *selfDelegations) get(validators []stakingValidator) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.refreshing && time.Since(s.refreshed) > selfDelegationRefresh {
+		s.refreshing = true
+		go func() {
+			amounts := querySelfDelegations(validators)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.amounts, s.refreshed, s.refreshing = amounts, time.Now(), false
+		}()
+	}
+
+	return s.amounts
+}
+
+// stakingTable describes the staking pool and lists all x/staking validators,
+// cometValidators is the CometBFT validator set to join them with.
+func stakingTable(cometValidators []gjson.Result, self *selfDelegations) (string, error) {
+	validatorsRPC, err := getFromAPI("/cosmos/staking/v1beta1/validators?pagination.limit=1000")
+	if err != nil {
+		return "", err
+	}
+	poolRPC, err := getFromAPI("/cosmos/staking/v1beta1/pool")
+	if err != nil {
+		return "", err
+	}
+	paramsRPC, err := getFromAPI("/cosmos/staking/v1beta1/params")
+	if err != nil {
+		return "", err
+	}
+	bondDenom := gjson.Get(paramsRPC, "params.bond_denom").String()
+
+	// the consensus key joins both sets
+	powers := map[string]int64{}
+	totalPower := int64(0)
+	for _, v := range cometValidators {
+		powers[v.Get("pub_key.value").String()] = v.Get("voting_power").Int()
+		totalPower += v.Get("voting_power").Int()
+	}
+
+	var validators []stakingValidator
+	for _, v := range gjson.Get(validatorsRPC, "validators").Array() {
+		tokens, _ := new(big.Int).SetString(v.Get("tokens").String(), 10)
+		if tokens == nil {
+			tokens = new(big.Int)
+		}
+		status := bondStatuses[v.Get("status").String()]
+		if v.Get("jailed").Bool() {
+			status += ", jailed"
+		}
+		validators = append(validators, stakingValidator{
+			moniker:     v.Get("description.moniker").String(),
+			operator:    v.Get("operator_address").String(),
+			status:      status,
+			tokens:      tokens,
+			commission:  v.Get("commission.commission_rates.rate").Float() * 100,
+			votingPower: powers[v.Get("consensus_pubkey.key").String()],
+		})
+	}
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].tokens.Cmp(validators[j].tokens) > 0
+	})
+
+	selfAmounts := self.get(validators)
+
+	bonded := gjson.Get(poolRPC, "pool.bonded_tokens").String()
+	notBonded := gjson.Get(poolRPC, "pool.not_bonded_tokens").String()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Bonded %s · Not Bonded %s · Bonded Ratio %.2f%% · %d Validators, %d in the active set\n\n",
+		formatCoin(bonded, bondDenom),
+		formatCoin(notBonded, bondDenom),
+		ratio(bonded, querySupply(bondDenom))*100,
+		len(validators), len(cometValidators),
+	)
+	fmt.Fprintf(&b, "%-20s %-52s %-17s %20s %10s %20s %8s\n", "MONIKER", "OPERATOR", "STATUS", "TOKENS ("+shorten(displaySymbol(bondDenom), 10)+")", "COMMISSION", "SELF-DELEGATION", "POWER")
+	for _, v := range validators {
+		share := 0.0
+		// don't divide by 0
+		if totalPower > 0 {
+			share = float64(v.votingPower) / float64(totalPower) * 100
+		}
+		fmt.Fprintf(&b, "%-20s %-52s %-17s %20s %9.2f%% %20s %7.2f%%\n",
+			shorten(v.moniker, 20),
+			v.operator,
+			v.status,
+			formatDisplayAmount(v.tokens.String(), bondDenom),
+			v.commission,
+			formatDisplayAmount(selfAmounts[v.operator], bondDenom),
+			share,
+		)
+	}
+
+	return b.String(), nil
+}
+
+// querySelfDelegations queries the amount each operator delegated to itself.
+func querySelfDelegations(validators []stakingValidator) map[string]string {
+	amounts := map[string]string{}
+	for _, v := range validators {
+		hrp, _, err := bech32Decode(v.operator)
+		if err != nil {
+			continue
+		}
+		delegator, err := convertBech32Prefix(v.operator, strings.TrimSuffix(hrp, "valoper"))
+		if err != nil {
+			continue
+		}
+
+		delegationRPC, err := getFromAPI("/cosmos/staking/v1beta1/validators/" + v.operator + "/delegations/" + delegator)
+		if err != nil {
+			continue
+		}
+		amounts[v.operator] = gjson.Get(delegationRPC, "delegation_response.balance.amount").String()
+	}
+
+	return amounts
+}
+
+// querySupply returns the total supply of the denom or an empty string if it
+// is unknown.
+func querySupply(denom string) string {
+	supplyRPC, err := getFromAPI("/cosmos/bank/v1beta1/supply/by_denom?denom=" + url.QueryEscape(denom))
+	if err != nil {
+		// Cosmos SDK before v0.47
+		supplyRPC, err = getFromAPI("/cosmos/bank/v1beta1/supply/" + url.PathEscape(denom))
+		if err != nil {
+			return ""
+		}
+	}
+
+	return gjson.Get(supplyRPC, "amount.amount").String()
+}
+
+// ratio divides two decimal amounts of arbitrary size, it's 0 if either of
+// them is invalid or the divisor is 0.
+func ratio(dividend, divisor string) float64 {
+	a, ok := new(big.Float).SetString(dividend)
+	if !ok {
+		return 0
+	}
+	b, ok := new(big.Float).SetString(divisor)
+	if !ok || b.Sign() == 0 {
+		return 0
+	}
+	r, _ := new(big.Float).Quo(a, b).Float64()
+
+	return r
+}
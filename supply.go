@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// writeSupply writes the total supply, the inflation and the community pool.
+// Exits when the context expires.
+func writeSupply(ctx context.Context, t textWidget, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			supply, err := supplySummary()
+			if err != nil {
+				supply = err.Error()
+			}
+
+			t.Reset()
+			if err := t.Write(supply); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// supplySummary describes the x/bank supply, the x/mint inflation and the
+// x/distribution community pool. Chains without x/mint show no inflation.
+func supplySummary() (string, error) {
+	supplyRPC, err := getFromAPI("/cosmos/bank/v1beta1/supply?pagination.limit=1000")
+	if err != nil {
+		return "", err
+	}
+
+	inflation, provisions := "-", "-"
+	if inflationRPC, err := getFromAPI("/cosmos/mint/v1beta1/inflation"); err == nil {
+		inflation = fmt.Sprintf("%.2f%%", gjson.Get(inflationRPC, "inflation").Float()*100)
+	}
+	if provisionsRPC, err := getFromAPI("/cosmos/mint/v1beta1/annual_provisions"); err == nil {
+		paramsRPC, _ := getFromAPI("/cosmos/mint/v1beta1/params")
+		provisions = formatCoin(truncateDecimal(gjson.Get(provisionsRPC, "annual_provisions").String()), gjson.Get(paramsRPC, "params.mint_denom").String())
+	}
+
+	pool := "-"
+	if poolRPC, err := getFromAPI("/cosmos/distribution/v1beta1/community_pool"); err == nil {
+		var coins []string
+		for _, coin := range gjson.Get(poolRPC, "pool").Array() {
+			coins = append(coins, formatCoin(truncateDecimal(coin.Get("amount").String()), coin.Get("denom").String()))
+		}
+		if len(coins) > 0 {
+			pool = strings.Join(coins, ", ")
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Inflation %s · Annual Provisions %s\n", inflation, provisions)
+	fmt.Fprintf(&b, "Community Pool %s\n", pool)
+	fmt.Fprintf(&b, "Supply %s\n", formatCoins(gjson.Get(supplyRPC, "supply")))
+
+	return b.String(), nil
+}
+
+// truncateDecimal drops the fractional part of a decimal amount, the
+// x/mint and x/distribution amounts carry 18 decimals.
+func truncateDecimal(amount string) string {
+	if i := strings.Index(amount, "."); i >= 0 {
+		return amount[:i]
+	}
+
+	return amount
+}
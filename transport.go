@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sacOO7/gowebsocket"
+	"gopkg.in/resty.v1"
+)
+
+// rpcHeader is sent with every RPC request and websocket handshake.
+var rpcHeader = http.Header{}
+
+// headerFlag collects the repeated -header flags.
+type headerFlag []string
+
+func (h *headerFlag) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlag) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+// setupRPC checks the -rpc and -ws URLs and collects the headers of the RPC
+// and the endpoints of the chain, with the basic auth of each -rpc URL.
+func setupRPC() error {
+	for _, h := range givenHeaders {
+		i := strings.Index(h, ":")
+		if i <= 0 {
+			return fmt.Errorf("invalid header %q, expected \"Name: value\"", h)
+		}
+		rpcHeader.Add(strings.TrimSpace(h[:i]), strings.TrimSpace(h[i+1:]))
+	}
+
+	if *givenWS != "" {
+		u, err := url.Parse(*givenWS)
+		if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+			return fmt.Errorf("invalid websocket URL %q, expected ws(s)://host[:port][/path]", *givenWS)
+		}
+	}
+
+	if *givenRPC == "" {
+		ws := *givenWS
+		if ws == "" {
+			ws = getWsUrl() + "/websocket"
+		}
+		endpoints.list = []rpcEndpoint{{url: getUrl("http", *ssl), ws: ws}}
+		return nil
+	}
+
+	for _, given := range strings.Split(*givenRPC, ",") {
+		e, err := parseEndpoint(strings.TrimSpace(given))
+		if err != nil {
+			return err
+		}
+		endpoints.list = append(endpoints.list, e)
+	}
+	if *givenWS != "" {
+		if len(endpoints.list) > 1 {
+			return errors.New("-ws can't be combined with several RPC URLs, their websockets are derived from the RPCs")
+		}
+		endpoints.list[0].ws = *givenWS
+	}
+
+	return nil
+}
+
+// parseEndpoint parses an RPC URL and derives its websocket.
+func parseEndpoint(given string) (rpcEndpoint, error) {
+	u, err := url.Parse(given)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return rpcEndpoint{}, fmt.Errorf("invalid RPC URL %q, expected http(s)://host[:port][/path]", given)
+	}
+
+	e := rpcEndpoint{}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		e.auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(u.User.Username()+":"+password))
+	}
+	u.User = nil
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	e.url = u.String()
+
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path += "/websocket"
+	e.ws = u.String()
+
+	return e, nil
+}
+
+// endpointHeaders returns the headers sent to an endpoint, the basic auth of
+// its URL unless an Authorization header is given.
+func endpointHeaders(e rpcEndpoint) http.Header {
+	header := rpcHeader.Clone()
+	if e.auth != "" && header.Get("Authorization") == "" {
+		header.Set("Authorization", e.auth)
+	}
+
+	return header
+}
+
+// restyHeaders converts headers for resty.
+func restyHeaders(header http.Header) map[string]string {
+	headers := map[string]string{}
+	for name, values := range header {
+		headers[name] = strings.Join(values, ", ")
+	}
+
+	return headers
+}
+
+// clientTLS is the TLS config of the RPC and websocket connections.
+var clientTLS = &tls.Config{}
+
+// rpcClient is the HTTP client of the RPC. The Cosmos SDK API is queried with
+// the default client, which doesn't take the TLS options of the RPC.
+var rpcClient = resty.New()
+
+// setupTLS builds the TLS config from the flags and applies it with the proxy
+// to the RPC client, the default client only gets the proxy.
+func setupTLS() error {
+	clientTLS = &tls.Config{
+		ServerName:         *givenSNI,
+		InsecureSkipVerify: *givenInsecure,
+	}
+
+	if *givenCA != "" {
+		pem, err := ioutil.ReadFile(*givenCA)
+		if err != nil {
+			return err
+		}
+		clientTLS.RootCAs = x509.NewCertPool()
+		if !clientTLS.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", *givenCA)
+		}
+	}
+
+	if *givenCert != "" || *givenKey != "" {
+		if *givenCert == "" || *givenKey == "" {
+			return errors.New("mutual TLS needs both -cert and -key")
+		}
+		cert, err := tls.LoadX509KeyPair(*givenCert, *givenKey)
+		if err != nil {
+			return err
+		}
+		clientTLS.Certificates = []tls.Certificate{cert}
+	}
+
+	rpcClient.SetTransport(newTransport())
+	resty.SetTransport(newProxyTransport())
+
+	return nil
+}
+
+// newSocket creates a websocket to an endpoint with the headers, the TLS
+// config and the proxy of the flags.
+func newSocket(e rpcEndpoint) gowebsocket.Socket {
+	socket := gowebsocket.New(e.ws)
+	for name, values := range endpointHeaders(e) {
+		socket.RequestHeader[name] = values
+	}
+
+	u, err := url.Parse(e.ws)
+	if err != nil {
+		return socket
+	}
+	// the proxies are picked by the scheme of the HTTP upgrade
+	dial := proxyDialer(&url.URL{Scheme: strings.Replace(u.Scheme, "ws", "http", 1), Host: u.Host})
+	socket.WebsocketDialer.NetDialContext = dial
+	if u.Scheme != "wss" {
+		return socket
+	}
+
+	// gowebsocket replaces the TLS config of its dialer when connecting, so
+	// the TLS handshake is done while dialing and the websocket speaks plain
+	// ws over the TLS connection
+	socket.RequestHeader.Set("Host", u.Host)
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	u.Scheme = "ws"
+	socket.Url = u.String()
+	socket.WebsocketDialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialTLS(ctx, dial, network, addr)
+	}
+
+	return socket
+}
+
+// dialTLS opens a TLS connection with the TLS config of the flags over a
+// connection of the dialer.
+func dialTLS(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), network, addr string) (net.Conn, error) {
+	conn, err := dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	config := clientTLS.Clone()
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
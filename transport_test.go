@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"gopkg.in/resty.v1"
+)
+
+func TestSetupTLS(t *testing.T) {
+	saved := *givenSNI
+	*givenSNI = "node.internal"
+	t.Cleanup(func() {
+		*givenSNI = saved
+		if err := setupTLS(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if err := setupTLS(); err != nil {
+		t.Fatal(err)
+	}
+
+	rpc, ok := rpcClient.GetClient().Transport.(*http.Transport)
+	if !ok || rpc.TLSClientConfig == nil || rpc.TLSClientConfig.ServerName != "node.internal" {
+		t.Errorf("the RPC client doesn't request node.internal")
+	}
+	api, ok := resty.DefaultClient.GetClient().Transport.(*http.Transport)
+	if !ok || api.Proxy == nil {
+		t.Fatal("the default client has no proxy")
+	}
+	if api.TLSClientConfig != nil {
+		t.Errorf("the default client took the TLS options of the RPC")
+	}
+}
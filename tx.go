@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/base64"
+)
+
+// decodedTx is the part of a Cosmos SDK transaction shown by gex.
+type decodedTx struct {
+	// type URLs of the messages, e.g. /cosmos.bank.v1beta1.MsgSend
+	messages []string
+	fee      []txCoin
+	gasLimit uint64
+}
+
+// txCoin is an amount of a denom as encoded in a transaction.
+type txCoin struct {
+	denom  string
+	amount string
+}
+
+// decodeTx decodes a base64 encoded cosmos.tx.v1beta1.TxRaw.
+func decodeTx(tx string) (decodedTx, error) {
+	var decoded decodedTx
+
+	raw, err := base64.StdEncoding.DecodeString(tx)
+	if err != nil {
+		return decoded, err
+	}
+	txRaw, err := protoFields(raw)
+	if err != nil {
+		return decoded, err
+	}
+
+	for _, f := range txRaw {
+		if f.wireType != wireBytes {
+			continue
+		}
+		switch f.number {
+		// TxRaw.body_bytes
+		case 1:
+			if decoded.messages, err = decodeTxBody(f.bytes); err != nil {
+				return decoded, err
+			}
+		// TxRaw.auth_info_bytes
+		case 2:
+			if decoded.fee, decoded.gasLimit, err = decodeAuthInfo(f.bytes); err != nil {
+				return decoded, err
+			}
+		}
+	}
+
+	return decoded, nil
+}
+
+// decodeTxBody returns the type URLs of the messages of a TxBody.
+func decodeTxBody(b []byte) ([]string, error) {
+	body, err := protoFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	for _, bf := range body {
+		// TxBody.messages
+		if bf.number != 1 || bf.wireType != wireBytes {
+			continue
+		}
+		any, err := protoFields(bf.bytes)
+		if err != nil {
+			return nil, err
+		}
+		for _, af := range any {
+			// Any.type_url
+			if af.number == 1 && af.wireType == wireBytes {
+				messages = append(messages, string(af.bytes))
+			}
+		}
+	}
+
+	return messages, nil
+}
+
+// decodeAuthInfo returns the fee amount and the gas limit of an AuthInfo.
+func decodeAuthInfo(b []byte) ([]txCoin, uint64, error) {
+	authInfo, err := protoFields(b)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var coins []txCoin
+	var gasLimit uint64
+	for _, af := range authInfo {
+		// AuthInfo.fee
+		if af.number != 2 || af.wireType != wireBytes {
+			continue
+		}
+		fee, err := protoFields(af.bytes)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, ff := range fee {
+			switch {
+			// Fee.amount
+			case ff.number == 1 && ff.wireType == wireBytes:
+				coin, err := protoFields(ff.bytes)
+				if err != nil {
+					return nil, 0, err
+				}
+				var c txCoin
+				for _, cf := range coin {
+					// Coin.denom and Coin.amount
+					if cf.number == 1 && cf.wireType == wireBytes {
+						c.denom = string(cf.bytes)
+					}
+					if cf.number == 2 && cf.wireType == wireBytes {
+						c.amount = string(cf.bytes)
+					}
+				}
+				coins = append(coins, c)
+			// Fee.gas_limit
+			case ff.number == 2 && ff.wireType == wireVarint:
+				gasLimit = ff.varint
+			}
+		}
+	}
+
+	return coins, gasLimit, nil
+}
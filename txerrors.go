@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// failureWindow is the time span the failure counter covers.
+const failureWindow = 10 * time.Minute
+
+// sdkErrors names the errors of the Cosmos SDK "sdk" codespace.
+var sdkErrors = map[int64]string{
+	2:  "tx parse error",
+	3:  "invalid sequence",
+	4:  "unauthorized",
+	5:  "insufficient funds",
+	6:  "unknown request",
+	7:  "invalid address",
+	8:  "invalid pubkey",
+	9:  "unknown address",
+	10: "invalid coins",
+	11: "out of gas",
+	12: "memo too large",
+	13: "insufficient fee",
+	14: "maximum number of signatures exceeded",
+	15: "no signatures supplied",
+	16: "failed to marshal JSON bytes",
+	17: "failed to unmarshal JSON bytes",
+	18: "invalid request",
+	19: "tx already in mempool",
+	20: "mempool is full",
+	21: "tx too large",
+	22: "key not found",
+	23: "invalid account password",
+	24: "tx intended signer does not match the given signer",
+	25: "invalid gas adjustment",
+	26: "invalid height",
+	27: "invalid version",
+	28: "invalid chain-id",
+	29: "invalid type",
+	30: "tx timeout height",
+	31: "unknown extension options",
+	32: "incorrect account sequence",
+	33: "failed packing protobuf message to Any",
+	34: "failed unpacking protobuf message from Any",
+	35: "internal logic error",
+	36: "conflict",
+	37: "feature not supported",
+	38: "not found",
+	39: "Internal IO error",
+	40: "error in app.toml",
+	41: "invalid gas limit",
+	42: "tx timeout",
+}
+
+// txError names the error of a failed transaction, e.g. sdk/5 insufficient
+// funds. Errors of other codespaces are named by their codespace and code.
+func txError(codespace string, code int64) string {
+	if codespace == "" {
+		codespace = "sdk"
+	}
+	if name, ok := sdkErrors[code]; ok && codespace == "sdk" {
+		return fmt.Sprintf("%s/%d %s", codespace, code, name)
+	}
+	if codespace == "undefined" && code == 1 {
+		return "undefined/1 internal"
+	}
+
+	return fmt.Sprintf("%s/%d", codespace, code)
+}
+
+// Failures counts the failed transactions by their error
+type Failures struct {
+	mu     sync.Mutex
+	window []failureSeen
+	totals map[string]int
+}
+
+// failureSeen is a failed transaction
+type failureSeen struct {
+	at     time.Time
+	reason string
+}
+
+// failureStats are the counts of an error.
+type failureStats struct {
+	reason   string
+	inWindow int
+	total    int
+}
+
+// count counts a Tx event received over the websocket if its transaction failed.
+func (f *Failures) count(message string) {
+	result := gjson.Get(message, "result.data.value.TxResult.result")
+	code := result.Get("code").Int()
+	if code == 0 {
+		return
+	}
+	reason := txError(result.Get("codespace").String(), code)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.totals == nil {
+		f.totals = map[string]int{}
+	}
+
+	f.window = append(f.window, failureSeen{at: time.Now(), reason: reason})
+	f.totals[reason]++
+}
+
+// stats returns the counts of all errors seen, the most frequent in the
+// window first.
+func (f *Failures) stats() []failureStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// drop the failures that left the window
+	cutoff := time.Now().Add(-failureWindow)
+	i := 0
+	for i < len(f.window) && f.window[i].at.Before(cutoff) {
+		i++
+	}
+	f.window = f.window[i:]
+
+	inWindow := map[string]int{}
+	for _, seen := range f.window {
+		inWindow[seen.reason]++
+	}
+
+	var stats []failureStats
+	for reason, total := range f.totals {
+		stats = append(stats, failureStats{reason: reason, inWindow: inWindow[reason], total: total})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].inWindow != stats[j].inWindow {
+			return stats[i].inWindow > stats[j].inWindow
+		}
+		if stats[i].total != stats[j].total {
+			return stats[i].total > stats[j].total
+		}
+		return stats[i].reason < stats[j].reason
+	})
+
+	return stats
+}
+
+// txStatus describes the result of a Tx event received over the websocket
+// and returns the color to highlight it with.
+func txStatus(message string) (string, cell.Color) {
+	result := gjson.Get(message, "result.data.value.TxResult.result")
+	code := result.Get("code").Int()
+	if code == 0 {
+		return "✔ ok", cell.ColorGreen
+	}
+
+	return "✖ " + txError(result.Get("codespace").String(), code), cell.ColorRed
+}
+
+// writeFailures writes the failed transactions by their error.
+// Exits when the context expires.
+func writeFailures(ctx context.Context, info Info, t *text.Text, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			stats := info.failures.stats()
+			if len(stats) == 0 {
+				continue
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "%-36s %6s %6s\n", "ERROR", "WINDOW", "TOTAL")
+			for _, s := range stats {
+				fmt.Fprintf(&b, "%-36s %6d %6d\n", shorten(s.reason, 36), s.inWindow, s.total)
+			}
+
+			t.Reset()
+			if err := t.Write(b.String()); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
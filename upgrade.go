@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// writeUpgradePlan writes the upgrade plan of x/upgrade with its ETA and a
+// banner once the chain halts at the upgrade height.
+// Exits when the context expires.
+func writeUpgradePlan(ctx context.Context, info Info, t textWidget, delay time.Duration) {
+	ticker := time.NewTicker(delay)
+	defer ticker.Stop()
+
+	// the last plan that loaded, a halted node often stops serving the API
+	var plan gjson.Result
+	for {
+		select {
+		case <-ticker.C:
+			planRPC, err := getFromAPI("/cosmos/upgrade/v1beta1/current_plan")
+			t.Reset()
+			if err == nil {
+				plan = gjson.Get(planRPC, "plan")
+			}
+			planned := plan.Exists() && plan.Type != gjson.Null
+			if err != nil && !planned {
+				if err := t.Write(err.Error()); err != nil {
+					panic(err)
+				}
+				continue
+			}
+			// shown below the last plan that loaded
+			stale := ""
+			if err != nil {
+				stale = "\n✖️ " + err.Error()
+			}
+
+			if !planned {
+				if err := t.Write("no upgrade planned"); err != nil {
+					panic(err)
+				}
+				continue
+			}
+
+			blocks := info.blocks.snapshot()
+			height := plan.Get("height").Int()
+			remaining := height - blocks.height
+			secondsPerBlock := 0.0
+			if blocks.amount != 0 {
+				secondsPerBlock = float64(blocks.secondsPassed) / float64(blocks.amount)
+			}
+
+			// the chain halts before committing the upgrade height
+			if remaining <= 1 {
+				banner := fmt.Sprintf("⚠ UPGRADE %s AT HEIGHT %s ⚠\n", plan.Get("name").String(), numberWithComma(height))
+				// no new block for a few block times means the chain is waiting for the new binary
+				if secondsPerBlock > 0 && time.Since(blocks.lastBlockTime).Seconds() > 3*secondsPerBlock {
+					banner += "CHAIN HALTED, WAITING FOR THE UPGRADED BINARY\n"
+				}
+				if err := t.Write(banner, text.WriteCellOpts(cell.FgColor(cell.ColorRed), cell.Bold())); err != nil {
+					panic(err)
+				}
+				if stale != "" {
+					if err := t.Write(stale); err != nil {
+						panic(err)
+					}
+				}
+				continue
+			}
+
+			eta := "unknown"
+			if secondsPerBlock > 0 {
+				d := time.Duration(float64(remaining) * secondsPerBlock * float64(time.Second))
+				eta = fmt.Sprintf("in %s, %s", formatDuration(d), time.Now().Add(d).Format("2006-01-02 03:04 PM"))
+			}
+			if err := t.Write(fmt.Sprintf("%s at height %s\n%s blocks left, ETA %s%s",
+				plan.Get("name").String(),
+				numberWithComma(height),
+				numberWithComma(remaining),
+				eta,
+				stale,
+			)); err != nil {
+				panic(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
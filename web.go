@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/widgets/donut"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/tidwall/gjson"
+)
+
+// serveCommand is the subcommand serving the web dashboard.
+const serveCommand = "serve"
+
+// webRollLines is how many lines the rolling panels of the web dashboard keep.
+const webRollLines = 200
+
+// webUpdateInterval is how often the web dashboard sends the changed panels
+// to the browsers.
+const webUpdateInterval = 500 * time.Millisecond
+
+// webPage is the HTML of the web dashboard, laid out like the Overview page.
+//
+//go:embed web.html
+var webPage []byte
+
+// textWidget is where the writers write text to, a text widget of the
+// terminal or a panel of the web dashboard.
+type textWidget interface {
+	Reset()
+	Write(text string, wOpts ...text.WriteOption) error
+}
+
+// percentWidget is where writeBlockDonut writes the round step to, a donut of
+// the terminal or a panel of the web dashboard.
+type percentWidget interface {
+	Percent(p int, opts ...donut.Option) error
+}
+
+// webPanel is a widget of the web dashboard, it keeps what was written to it
+// until the browsers fetch it. The colors of the terminal are dropped.
+type webPanel struct {
+	mu      sync.Mutex
+	text    string
+	percent int
+	roll    bool
+}
+
+func (p *webPanel) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.text = ""
+}
+
+func (p *webPanel) Write(s string, wOpts ...text.WriteOption) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.text += s
+	// rolling panels keep the latest lines like the terminal scrolls them away
+	if p.roll {
+		if lines := strings.Split(p.text, "\n"); len(lines) > webRollLines {
+			p.text = strings.Join(lines[len(lines)-webRollLines:], "\n")
+		}
+	}
+
+	return nil
+}
+
+func (p *webPanel) Percent(percent int, opts ...donut.Option) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.percent = percent
+
+	return nil
+}
+
+// webPanelState is a panel as sent to the browsers.
+type webPanelState struct {
+	Text    string `json:"text"`
+	Percent int    `json:"percent"`
+}
+
+// webDashboard serves the panels of the Overview page to browsers and pushes
+// their changes with Server-Sent Events.
+type webDashboard struct {
+	panels map[string]*webPanel
+}
+
+// panel adds a panel with its initial text, the id matches the element of
+// web.html.
+func (d *webDashboard) panel(id string, initial string, roll bool) *webPanel {
+	p := &webPanel{text: initial, roll: roll}
+	d.panels[id] = p
+
+	return p
+}
+
+// snapshot encodes the state of all panels.
+func (d *webDashboard) snapshot() []byte {
+	states := map[string]webPanelState{}
+	for id, p := range d.panels {
+		p.mu.Lock()
+		states[id] = webPanelState{Text: p.text, Percent: p.percent}
+		p.mu.Unlock()
+	}
+
+	snapshot, err := json.Marshal(states)
+	if err != nil {
+		panic(err)
+	}
+
+	return snapshot
+}
+
+// servePage serves web.html.
+func (d *webDashboard) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(webPage)
+}
+
+// serveEvents sends the panels whenever they changed until the browser
+// disconnects.
+func (d *webDashboard) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ticker := time.NewTicker(webUpdateInterval)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		if snapshot := d.snapshot(); !bytes.Equal(snapshot, last) {
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", snapshot); err != nil {
+				return
+			}
+			flusher.Flush()
+			last = snapshot
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// serveDashboard collects the data of the Overview page into the panels of
+// the web dashboard and serves it on the -addr address.
+func serveDashboard(ctx context.Context, info Info, networkStatus gjson.Result, genesisInfo gjson.Result, maxBlockSize int64, connectionSignal chan string) error {
+	d := &webDashboard{panels: map[string]*webPanel{}}
+	widgets := overviewWidgets{
+		network:         d.panel("network", "", false),
+		health:          d.panel("health", "⌛ loading", false),
+		time:            d.panel("time", time.Now().Format("2006-01-02\n03:04:05 PM"), false),
+		peers:           d.panel("peers", "0", false),
+		supply:          d.panel("supply", "⌛ loading", false),
+		blocks:          d.panel("blocks", networkStatus.Get("result.sync_info.latest_block_height").String(), false),
+		secondsPerBlock: d.panel("seconds-per-block", "0", false),
+		validators:      d.panel("validators", "List available validators.\n\n", false),
+		gasMax:          d.panel("gas-max", "", false),
+		gasAvgBlock:     d.panel("gas-avg-block", "", false),
+		gasAvgTx:        d.panel("gas-avg-tx", "", false),
+		latestGas:       d.panel("latest-gas", "", false),
+		upgrade:         d.panel("upgrade", "⌛ loading", false),
+		transactions:    d.panel("transactions", "Transactions will appear as soon as they are confirmed in a block.\n\n", true),
+		// the list of the Transactions page isn't shown
+		txList: &webPanel{roll: true},
+		round:  d.panel("round", "", false),
+	}
+	d.panel("max-block-size", byteCountDecimal(maxBlockSize), false)
+
+	writeOverview(ctx, info, widgets, networkStatus.Get("result.node_info.network").String(), newTxFilter(*givenFilter), connectionSignal, genesisInfo)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.servePage)
+	mux.HandleFunc("/events", d.serveEvents)
+	(&jsonAPI{info: info, network: networkStatus.Get("result.node_info.network").String()}).register(mux)
+
+	fmt.Printf("Serving the dashboard of %s on %s\n", getHttpUrl(), *givenAddr)
+
+	return http.ListenAndServe(*givenAddr, mux)
+}